@@ -5,18 +5,24 @@ package app
 
 import (
 	"app-lab-desktop/internal/appui"
+	"app-lab-desktop/internal/backup"
 	"app-lab-desktop/internal/board"
 	"app-lab-desktop/internal/featureflags"
 	"app-lab-desktop/internal/fs"
 	"app-lab-desktop/internal/fs/opener"
 	"app-lab-desktop/internal/learn"
+	"app-lab-desktop/internal/monitor"
 	"app-lab-desktop/internal/network"
 	"app-lab-desktop/internal/network/ethernet"
 	"app-lab-desktop/internal/network/wifi"
+	"app-lab-desktop/internal/session"
 	"app-lab-desktop/internal/terminal"
+	"app-lab-desktop/internal/tunnel"
 	"app-lab-desktop/internal/update"
+	"app-lab-desktop/internal/vault"
 
 	"fmt"
+	"time"
 )
 
 // Board check management
@@ -25,37 +31,93 @@ func (a *App) IsBoard() bool {
 }
 
 func (a *App) NeedsImageUpdate() bool {
-	return a.selectedBoard.IsR0Build()
+	return a.session().Board.IsR0Build()
 }
 
 // Orchestrator URL management
 func (a *App) GetOrchestratorURL() (string, error) {
-	return a.selectedBoard.GetOrchestratorURL()
+	return a.GetOrchestratorURLForBoard("")
+}
+
+func (a *App) GetOrchestratorURLForBoard(boardID string) (string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return "", err
+	}
+	return s.Board.GetOrchestratorURL()
 }
 
 // WiFi management
 func (a *App) ConnectToWiFi(ssid, password string) error {
-	return wifi.Connect(a.ctx(), a.selectedBoard.Conn, ssid, password)
+	return a.ConnectToWiFiForBoard("", ssid, password)
+}
+
+func (a *App) ConnectToWiFiForBoard(boardID, ssid, password string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return wifi.Connect(s.Context(), s.Board.Conn(), ssid, password)
 }
 
 func (a *App) ListSSIDs() ([]string, error) {
-	return wifi.ListSSIDs(a.ctx(), a.selectedBoard.Conn)
+	return a.ListSSIDsForBoard("")
+}
+
+func (a *App) ListSSIDsForBoard(boardID string) ([]string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return wifi.ListSSIDs(s.Context(), s.Board.Conn())
 }
 
 func (a *App) GetWiFiStatus() (wifi.WifiStatus, error) {
-	return wifi.GetWiFiStatus(a.ctx(), a.selectedBoard.Conn)
+	return a.GetWiFiStatusForBoard("")
+}
+
+func (a *App) GetWiFiStatusForBoard(boardID string) (wifi.WifiStatus, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return wifi.DisconnectedStatus, err
+	}
+	return wifi.GetWiFiStatus(s.Context(), s.Board.Conn())
 }
 
 func (a *App) GetEthStatus() (ethernet.EthStatus, error) {
-	return ethernet.GetEthStatus(a.ctx(), a.selectedBoard.Conn)
+	return a.GetEthStatusForBoard("")
+}
+
+func (a *App) GetEthStatusForBoard(boardID string) (ethernet.EthStatus, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return ethernet.DisconnectedStatus, err
+	}
+	return ethernet.GetEthStatus(s.Context(), s.Board.Conn())
 }
 
 func (a *App) GetInternetStatus() (bool, error) {
-	return network.GetInternetStatus(a.ctx(), a.selectedBoard.Conn)
+	return a.GetInternetStatusForBoard("")
+}
+
+func (a *App) GetInternetStatusForBoard(boardID string) (bool, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return false, err
+	}
+	return network.GetInternetStatus(s.Context(), s.Board.Conn())
 }
 
 func (a *App) GetConnectionName() (*string, error) {
-	return network.GetConnectionName(a.ctx(), a.selectedBoard.Conn)
+	return a.GetConnectionNameForBoard("")
+}
+
+func (a *App) GetConnectionNameForBoard(boardID string) (*string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return network.GetConnectionName(s.Context(), s.Board.Conn())
 }
 
 // Feature flags management
@@ -91,7 +153,8 @@ func (a *App) ApplyBoardUpdate(onlyArduino bool, origin string) (*bool, error) {
 }
 
 func (a *App) GetBoardUpdateLogs(origin string) error {
-	return update.GetBoardUpdateLogsStreamV1Request(a.ctx(), origin)
+	s := a.session()
+	return update.GetBoardUpdateLogsStreamV1Request(s.Context(), origin)
 }
 
 // Board list management
@@ -103,34 +166,379 @@ func (a *App) SelectBoard(id string, password string) error {
 	return a.selectBoard(id, password)
 }
 
+func (a *App) AddManualBoard(address string, port int, name string, password string) (*board.Board, error) {
+	return a.addManualBoard(address, port, name, password)
+}
+
+func (a *App) RemoveManualBoard(id string) error {
+	return a.removeManualBoard(id)
+}
+
+// Board registry management
+func (a *App) GetKnownBoards() ([]board.KnownBoard, error) {
+	return board.KnownBoards()
+}
+
+func (a *App) SetBoardNickname(identity string, nickname string) error {
+	return board.SetNickname(identity, nickname)
+}
+
+func (a *App) SetPreferredConnection(identity string, protocol string) error {
+	return board.SetPreferredProtocol(identity, protocol)
+}
+
+func (a *App) ForgetBoard(identity string) error {
+	return board.ForgetBoard(identity)
+}
+
+// Board credentials management
+func (a *App) GetCredentialsStatus() (vault.Status, error) {
+	v, err := board.Credentials()
+	if err != nil {
+		return vault.Status{}, err
+	}
+	return v.Status(), nil
+}
+
+func (a *App) UnlockCredentials(passphrase string) error {
+	v, err := board.Credentials()
+	if err != nil {
+		return err
+	}
+	return v.Unlock(passphrase)
+}
+
+func (a *App) LockCredentials() error {
+	v, err := board.Credentials()
+	if err != nil {
+		return err
+	}
+	v.Lock()
+	return nil
+}
+
+func (a *App) ListStoredCredentials() ([]vault.Entry, error) {
+	v, err := board.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	return v.List(), nil
+}
+
+func (a *App) ForgetBoardPassword(identity string) error {
+	return board.ForgetPassword(identity)
+}
+
+func (a *App) RotateCredentialsKey(passphrase string) error {
+	v, err := board.Credentials()
+	if err != nil {
+		return err
+	}
+	return v.Rotate(passphrase)
+}
+
+// Board host keys management
+func (a *App) GetPinnedHostKeys() ([]board.PinnedHostKey, error) {
+	return board.PinnedHostKeys()
+}
+
+func (a *App) ResetHostKey(identity string) error {
+	return board.ResetHostKey(identity)
+}
+
+// Board SSH key management
+func (a *App) GetAppSSHPublicKey() (string, error) {
+	return board.AppPublicKey()
+}
+
+func (a *App) InstallSSHKey(boardID string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return s.Board.InstallKey(s.Context())
+}
+
+func (a *App) RemoveSSHKey(boardID string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return s.Board.RemoveKey(s.Context())
+}
+
+func (a *App) IsSSHKeyInstalled(boardID string) (bool, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return false, err
+	}
+	return board.IsAppKeyInstalled(s.Board.Conn())
+}
+
+// Board sessions management
+func (a *App) ConnectBoard(id string, password string) error {
+	return a.connectBoard(id, password)
+}
+
+func (a *App) DisconnectBoard(id string) error {
+	return a.sessions.Disconnect(a.ctx(), id)
+}
+
+func (a *App) SetActiveBoard(id string) error {
+	return a.sessions.SetActive(id)
+}
+
+func (a *App) GetConnectedBoards() []*board.Board {
+	return a.sessions.Boards()
+}
+
+func (a *App) GetActiveBoard() *board.Board {
+	return a.session().Board
+}
+
+func (a *App) GetBoardConnectionState(boardID string) (session.State, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return "", err
+	}
+	return s.State(), nil
+}
+
+// CollectDiagnostics writes a zip with the logs and the state of the board, and the
+// logs of the app, to dest, asking for it if empty, and returns its path. The
+// secrets are redacted.
+func (a *App) CollectDiagnostics(boardID string, dest string) (string, error) {
+	return a.collectDiagnostics(boardID, dest)
+}
+
+// Board backup
+
+// CreateBackup writes a backup of the board apps, settings, Wi-Fi profiles and SSH
+// files to dest, asking for it if empty. The backup holds secrets.
+func (a *App) CreateBackup(boardID string, dest string) (BackupResult, error) {
+	manifest, path, err := a.createBackup(boardID, dest)
+	return BackupResult{Path: path, Manifest: manifest}, err
+}
+
+// PreviewRestore returns what restoring the backup src, asked for if empty, would do
+// to the board, without modifying it.
+func (a *App) PreviewRestore(boardID string, src string, overwriteSSH bool) (backup.Plan, error) {
+	return a.restoreBackup(boardID, src, true, overwriteSSH)
+}
+
+// RestoreBackup pushes the backup src onto the board. The SSH files already on the
+// board, e.g. its private keys, are only replaced if overwriteSSH is set. The returned
+// plan tells the actions performed, with the errors of the failed ones.
+func (a *App) RestoreBackup(boardID string, src string, overwriteSSH bool) (backup.Plan, error) {
+	return a.restoreBackup(boardID, src, false, overwriteSSH)
+}
+
+// Board power management
+
+// RebootBoard reboots the board and returns once it went down. The connection state
+// is "offline" until the board is reconnected, see the "board-connection-onchange" events.
+func (a *App) RebootBoard(boardID string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return a.sessions.Reboot(s)
+}
+
+// ShutdownBoard powers off the board and returns once it went down. The board is
+// reconnected if powered on again while the app runs.
+func (a *App) ShutdownBoard(boardID string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return a.sessions.Shutdown(s)
+}
+
+// RestartService restarts a systemd service of the board, e.g. "arduino-app-cli" for
+// the orchestrator.
+func (a *App) RestartService(boardID string, name string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return a.sessions.RestartService(s, name)
+}
+
 // Board name management
 func (a *App) GetBoardName() (string, error) {
-	return a.selectedBoard.GetName(a.ctx())
+	s := a.session()
+	return s.Board.GetName(s.Context())
 }
 
 func (a *App) SetBoardName(name string) error {
-	return a.selectedBoard.SetName(a.ctx(), name)
+	s := a.session()
+	return s.Board.SetName(s.Context(), name)
 }
 
 func (a *App) GetKeyboardLayout() (string, error) {
-	return a.selectedBoard.GetKeyboardLayout(a.ctx())
+	s := a.session()
+	return s.Board.GetKeyboardLayout(s.Context())
 }
 
 func (a *App) ListKeyboardLayouts() ([]board.KeyboardLayout, error) {
-	return a.selectedBoard.ListKeyboardLayouts()
+	return a.session().Board.ListKeyboardLayouts()
 }
 
 func (a *App) SetKeyboardLayout(layout string) error {
-	return a.selectedBoard.SetKeyboardLayout(a.ctx(), layout)
+	s := a.session()
+	return s.Board.SetKeyboardLayout(s.Context(), layout)
+}
+
+// GetTimezone returns the timezone of the active board, e.g. "Europe/Rome".
+func (a *App) GetTimezone() (string, error) {
+	return a.GetTimezoneForBoard("")
+}
+
+func (a *App) GetTimezoneForBoard(boardID string) (string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return "", err
+	}
+	return s.Board.GetTimezone(s.Context())
+}
+
+func (a *App) ListTimezones() ([]string, error) {
+	return a.ListTimezonesForBoard("")
+}
+
+func (a *App) ListTimezonesForBoard(boardID string) ([]string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Board.ListTimezones(s.Context())
+}
+
+func (a *App) SetTimezone(timezone string) error {
+	return a.SetTimezoneForBoard("", timezone)
+}
+
+func (a *App) SetTimezoneForBoard(boardID string, timezone string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return s.Board.SetTimezone(s.Context(), timezone)
+}
+
+// GetLocale returns the system locale of the active board, e.g. "en_US.UTF-8".
+func (a *App) GetLocale() (string, error) {
+	return a.GetLocaleForBoard("")
+}
+
+func (a *App) GetLocaleForBoard(boardID string) (string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return "", err
+	}
+	return s.Board.GetLocale(s.Context())
+}
+
+func (a *App) ListLocales() ([]string, error) {
+	return a.ListLocalesForBoard("")
+}
+
+func (a *App) ListLocalesForBoard(boardID string) ([]string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Board.ListLocales(s.Context())
+}
+
+func (a *App) SetLocale(locale string) error {
+	return a.SetLocaleForBoard("", locale)
+}
+
+func (a *App) SetLocaleForBoard(boardID string, locale string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return s.Board.SetLocale(s.Context(), locale)
+}
+
+// GetTimeSyncStatus returns the NTP clock synchronization state of the active board.
+func (a *App) GetTimeSyncStatus() (board.TimeSyncStatus, error) {
+	return a.GetTimeSyncStatusForBoard("")
+}
+
+func (a *App) GetTimeSyncStatusForBoard(boardID string) (board.TimeSyncStatus, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return board.TimeSyncStatus{}, err
+	}
+	return s.Board.GetTimeSyncStatus(s.Context())
+}
+
+func (a *App) SetTimeSync(enabled bool) error {
+	return a.SetTimeSyncForBoard("", enabled)
+}
+
+func (a *App) SetTimeSyncForBoard(boardID string, enabled bool) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return s.Board.SetTimeSync(s.Context(), enabled)
+}
+
+// GetSystemInfo returns the image version, resources and software versions of the active board.
+func (a *App) GetSystemInfo() (board.SystemInfo, error) {
+	return a.GetSystemInfoForBoard("")
+}
+
+func (a *App) GetSystemInfoForBoard(boardID string) (board.SystemInfo, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return board.SystemInfo{}, err
+	}
+	return s.Board.GetSystemInfo(s.Context())
+}
+
+// StartMonitoring samples the CPU, memory, temperature and container usage of the
+// board every intervalSeconds, 0 for the default, with the "resource-sample" events.
+// It stops with StopMonitoring or when the board is disconnected.
+func (a *App) StartMonitoring(boardID string, intervalSeconds int) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return a.monitors.Start(s.Context(), s.Board.Id, s.Board, time.Duration(intervalSeconds)*time.Second)
+}
+
+func (a *App) StopMonitoring(boardID string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return a.monitors.Stop(s.Board.Id)
+}
+
+// GetMonitoringHistory returns the last samples of a monitored board, the oldest first.
+func (a *App) GetMonitoringHistory(boardID string) ([]monitor.Sample, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return a.monitors.History(s.Board.Id)
 }
 
 // Board user password management
 func (a *App) IsUserPasswordSet() (bool, error) {
-	return a.selectedBoard.IsUserPasswordSet(a.ctx())
+	s := a.session()
+	return s.Board.IsUserPasswordSet(s.Context())
 }
 
 func (a *App) SetUserPassword(password string) error {
-	return a.selectedBoard.SetUserPassword(a.ctx(), password)
+	s := a.session()
+	return s.Board.SetUserPassword(s.Context(), password)
 }
 
 // File system management
@@ -139,32 +547,186 @@ func (a *App) OpenFile(path string) error {
 }
 
 func (a *App) GetFileTree(path string) (*fs.FSNode, error) {
-	return fs.GetFileTree(path, a.selectedBoard.Conn)
+	return a.GetFileTreeForBoard("", path)
+}
+
+func (a *App) GetFileTreeForBoard(boardID string, path string) (*fs.FSNode, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return fs.GetFileTree(path, s.Board.Conn())
 }
 
 func (a *App) GetFileContent(p string) (string, error) {
-	return fs.GetFileContent(p, a.selectedBoard.Conn)
+	return a.GetFileContentForBoard("", p)
+}
+
+func (a *App) GetFileContentForBoard(boardID string, p string) (string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return "", err
+	}
+	return fs.GetFileContent(p, s.Board.Conn())
 }
 
 func (a *App) WriteFileContent(path string, content string) error {
-	return fs.WriteFileContent(a.selectedBoard.Conn, path, content)
+	return a.WriteFileContentForBoard("", path, content)
+}
+
+func (a *App) WriteFileContentForBoard(boardID string, path string, content string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return fs.WriteFileContent(s.Board.Conn(), path, content)
 }
 
 func (a *App) RenameFile(oldPath string, newPath string) error {
-	return fs.RenameFile(a.selectedBoard.Conn, oldPath, newPath)
+	return a.RenameFileForBoard("", oldPath, newPath)
+}
+
+func (a *App) RenameFileForBoard(boardID string, oldPath string, newPath string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return fs.RenameFile(s.Board.Conn(), oldPath, newPath)
 }
 
 func (a *App) RemoveFile(path string) error {
-	return fs.RemoveFile(a.selectedBoard.Conn, path)
+	return a.RemoveFileForBoard("", path)
+}
+
+func (a *App) RemoveFileForBoard(boardID string, path string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return fs.RemoveFile(s.Board.Conn(), path)
 }
 
 func (a *App) CreateFolder(path string) error {
-	return fs.CreateFolder(a.selectedBoard.Conn, path)
+	return a.CreateFolderForBoard("", path)
+}
+
+func (a *App) CreateFolderForBoard(boardID string, path string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return fs.CreateFolder(s.Board.Conn(), path)
 }
 
 // Apps UI management
 func (a *App) OpenUIWhenReady(port int) error {
-	return appui.OpenUIWhenReady(a.ctx(), a.selectedBoard, port)
+	return a.OpenUIWhenReadyForBoard("", port)
+}
+
+func (a *App) OpenUIWhenReadyForBoard(boardID string, port int) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	// The app UI is opened through the proxy when it runs, so that its URL is stable.
+	if _, err := a.appProxy.URL(boardID, port); err == nil {
+		return a.appProxy.OpenUIWhenReady(s.Context(), boardID, s.Board, port)
+	}
+	return appui.OpenUIWhenReady(s.Context(), s.Board, port)
+}
+
+// GetAppURL returns the stable URL, served by the app proxy, of the app UI listening
+// on port. With an empty boardID, the URL follows the active board.
+func (a *App) GetAppURL(boardID string, port int) (string, error) {
+	if _, err := a.sessionFor(boardID); err != nil {
+		return "", err
+	}
+	return a.appProxy.URL(boardID, port)
+}
+
+// Tunnels management
+func (a *App) ListTunnels(boardID string) ([]tunnel.Info, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Board.ListTunnels(), nil
+}
+
+func (a *App) OpenTunnel(boardID string, tag string, boardPort int) (tunnel.Info, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return tunnel.Info{}, err
+	}
+	return s.Board.OpenTunnel(s.Context(), tag, boardPort)
+}
+
+// GetTunnelStats returns the traffic relayed by each tunnel of the board, they are
+// also emitted periodically with the "tunnel-stats" event.
+func (a *App) GetTunnelStats(boardID string) ([]tunnel.Stats, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Board.TunnelStats(), nil
+}
+
+// OpenReverseTunnel makes boardPort on the board reach localPort on this computer.
+func (a *App) OpenReverseTunnel(boardID string, tag string, boardPort int, localPort int) (tunnel.Info, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return tunnel.Info{}, err
+	}
+	return s.Board.OpenReverseTunnel(s.Context(), tag, boardPort, localPort)
+}
+
+func (a *App) CloseTunnelByTag(boardID string, tag string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return s.Board.CloseTunnelByTag(s.Context(), tag)
+}
+
+func (a *App) CloseTunnelByPort(boardID string, boardPort int) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return s.Board.CloseTunnelByPort(s.Context(), boardPort)
+}
+
+// Port forwards management
+func (a *App) ListForwards(boardID string) ([]board.ForwardStatus, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Board.ListForwards()
+}
+
+func (a *App) AddForward(boardID string, name string, boardPort int, localPort int) (board.ForwardStatus, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return board.ForwardStatus{}, err
+	}
+	return s.Board.AddForward(s.Context(), board.Forward{Name: name, BoardPort: boardPort, LocalPort: localPort})
+}
+
+func (a *App) RemoveForward(boardID string, name string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return s.Board.RemoveForward(s.Context(), name)
+}
+
+func (a *App) OpenForward(boardID string, name string) (board.ForwardStatus, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return board.ForwardStatus{}, err
+	}
+	return s.Board.OpenForward(s.Context(), name)
 }
 
 // Learn
@@ -182,5 +744,131 @@ func (a *App) GetTags() ([]learn.Tag, error) {
 
 // Open Terminal
 func (a *App) OpenBoardTerminal() error {
-	return terminal.OpenTerminal(a.ctx(), a.selectedBoard)
+	return a.OpenBoardTerminalForBoard("")
+}
+
+func (a *App) OpenBoardTerminalForBoard(boardID string) error {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return err
+	}
+	return terminal.OpenTerminal(s.Context(), s.Board)
+}
+
+// External terminal settings
+func (a *App) GetTerminalSettings() (terminal.Settings, error) {
+	return terminal.LoadSettings()
+}
+
+// SetTerminalSettings sets the command template opening the external terminal, with
+// the {script} or {command} placeholders. An empty command restores the detection.
+func (a *App) SetTerminalSettings(settings terminal.Settings) error {
+	return terminal.SaveSettings(settings)
+}
+
+func (a *App) ListTerminalEmulators() []terminal.Emulator {
+	return terminal.ListEmulators()
+}
+
+// TestTerminal opens the terminal of the template, or of the settings if empty.
+func (a *App) TestTerminal(template string) error {
+	return terminal.TestTerminal(template)
+}
+
+// Remote commands
+
+// RunCommand runs a command on the active board and returns its output and exit code.
+func (a *App) RunCommand(cmd string, args []string) (board.CommandResult, error) {
+	return a.RunCommandForBoard("", cmd, args)
+}
+
+func (a *App) RunCommandForBoard(boardID string, cmd string, args []string) (board.CommandResult, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return board.CommandResult{}, err
+	}
+	return s.Board.RunCommand(s.Context(), cmd, args)
+}
+
+// StartCommand runs a command on the active board in the background and returns its
+// id. The output is streamed with the "command-output" events, the exit code comes
+// with the "command-exit" one.
+func (a *App) StartCommand(cmd string, args []string) (string, error) {
+	return a.StartCommandForBoard("", cmd, args)
+}
+
+func (a *App) StartCommandForBoard(boardID string, cmd string, args []string) (string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return "", err
+	}
+	return a.startCommand(s, cmd, args), nil
+}
+
+// CancelCommand kills a command started with StartCommand.
+func (a *App) CancelCommand(id string) error {
+	return a.cancelCommand(id)
+}
+
+// Embedded terminals management
+
+// OpenEmbeddedTerminal opens a shell on the board, streamed with the "terminal-output"
+// events until the "terminal-exit" one. Several shells can be open on a board.
+func (a *App) OpenEmbeddedTerminal(boardID string, cols int, rows int) (terminal.ShellInfo, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return terminal.ShellInfo{}, err
+	}
+	return a.terminals.Open(s.Context(), s.Board.Id, s.Board.DisplayName(), s.Board.Conn(), cols, rows)
+}
+
+func (a *App) WriteEmbeddedTerminal(id string, data string) error {
+	return a.terminals.Write(id, []byte(data))
+}
+
+func (a *App) ResizeEmbeddedTerminal(id string, cols int, rows int) error {
+	return a.terminals.Resize(id, cols, rows)
+}
+
+func (a *App) CloseEmbeddedTerminal(id string) error {
+	return a.terminals.Close(id)
+}
+
+// StartTerminalRecording records the shell in the asciicast v2 format, until
+// StopTerminalRecording or the end of the shell, and returns the recording name.
+func (a *App) StartTerminalRecording(id string) (string, error) {
+	return a.terminals.StartRecording(id)
+}
+
+func (a *App) StopTerminalRecording(id string) error {
+	return a.terminals.StopRecording(id)
+}
+
+func (a *App) ListTerminalRecordings() ([]terminal.Recording, error) {
+	return terminal.ListRecordings()
+}
+
+// ExportTerminalRecording copies the recording to dest, asking for it if empty, and
+// returns the path it has been exported to.
+func (a *App) ExportTerminalRecording(name string, dest string) (string, error) {
+	return a.exportTerminalRecording(name, dest)
+}
+
+func (a *App) DeleteTerminalRecording(name string) error {
+	return terminal.DeleteRecording(name)
+}
+
+// ReplayTerminalRecording streams the recording like an embedded terminal, with the
+// "terminal-output" and "terminal-exit" events of the returned id, at the given speed.
+// CloseEmbeddedTerminal stops it.
+func (a *App) ReplayTerminalRecording(name string, speed float64) (terminal.ShellInfo, error) {
+	return a.terminals.Replay(name, speed)
+}
+
+func (a *App) ListEmbeddedTerminals(boardID string) ([]terminal.ShellInfo, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, err
+	}
+	return a.terminals.List(s.Board.Id), nil
 }
@@ -1,14 +1,19 @@
 package app
 
 import (
+	"app-lab-desktop/internal/appui"
 	"app-lab-desktop/internal/board"
 	"app-lab-desktop/internal/context"
 	"app-lab-desktop/internal/emoji"
 	"app-lab-desktop/internal/errors"
 	"app-lab-desktop/internal/fs"
 	"app-lab-desktop/internal/learn"
+	"app-lab-desktop/internal/monitor"
+	"app-lab-desktop/internal/session"
+	"app-lab-desktop/internal/terminal"
 	"app-lab-desktop/internal/update"
 	"fmt"
+	"sync"
 
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
@@ -19,17 +24,32 @@ type App struct {
 	version        string
 	updater        *update.Updater
 	learnSvc       *learn.Learn
+	watcher        *board.Watcher
+	stopWatcher    func()
+	stopStats      func()
+	boardsMu       sync.Mutex
 	detectedBoards []*board.Board
-	selectedBoard  *board.Board
+	sessions       *session.Manager
+	appProxy       *appui.Proxy
+	terminals      *terminal.Manager
+	monitors       *monitor.Manager
+	commandsMu     sync.Mutex
+	commands       map[string]func()
+	lastCommandID  int
 }
 
 func New(version string, learnSvc *learn.Learn) *App {
-	return &App{
-		ctxHolder:     context.NewHolder(),
-		version:       version,
-		learnSvc:      learnSvc,
-		selectedBoard: board.Noop(),
+	a := &App{
+		ctxHolder: context.NewHolder(),
+		version:   version,
+		learnSvc:  learnSvc,
+		commands:  make(map[string]func()),
 	}
+	a.sessions = session.NewManager(a.ctxHolder, a.onSessionEvent, a.findBoard)
+	a.appProxy = appui.NewProxy(a.resolveBoard)
+	a.terminals = terminal.NewManager(a.onTerminalEvent)
+	a.monitors = monitor.NewManager(a.onMonitorEvent)
+	return a
 }
 
 func (a *App) GetTitle() string {
@@ -49,7 +69,7 @@ func (a *App) GetAboutMessage() string {
 
 func (a *App) GetAssetMiddleware() assetserver.Middleware {
 	return assetserver.ChainMiddleware(
-		fs.FileContentAssetMiddleware(a.ctxHolder, a.selectedBoard),
+		fs.FileContentAssetMiddleware(a.ctxHolder, a.sessions.Board),
 		learn.AssetMiddleware(a.ctxHolder, a.learnSvc),
 		emoji.AssetMiddleware(a.ctxHolder),
 	)
@@ -57,6 +77,7 @@ func (a *App) GetAssetMiddleware() assetserver.Middleware {
 
 func (a *App) GetErrorFormatter() options.ErrorFormatter {
 	return errors.ChainErrorMiddleware([]errors.ErrorMiddleware{
-		errors.TunnelSSHAuthFailedMiddleware(),
+		errors.TunnelSSHHostKeyMismatchMiddleware(),
+		errors.TunnelSSHAuthFailedMiddleware(board.InvalidateCredentials),
 	})
 }
@@ -1,20 +1,36 @@
 package app
 
 import (
+	"app-lab-desktop/internal/backup"
 	"app-lab-desktop/internal/board"
+	"app-lab-desktop/internal/diagnostics"
+	"app-lab-desktop/internal/monitor"
+	"app-lab-desktop/internal/session"
+	"app-lab-desktop/internal/terminal"
+	"app-lab-desktop/internal/tunnel"
 	"app-lab-desktop/internal/update"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+const tunnelStatsInterval = 2 * time.Second
+
 func (a *App) Startup(ctx context.Context) {
 	a.ctxHolder.Set(ctx)
+	a.reportTunnelStats(ctx)
+	if err := a.appProxy.Start(); err != nil {
+		runtime.LogErrorf(ctx, "failed to start app proxy: %v", err)
+	}
 
-	if err := board.InstallToolingIfMissing(ctx); err != nil {
-		runtime.LogErrorf(ctx, "failed to initialize board: %v", err)
+	toolingErr := board.InstallToolingIfMissing(ctx)
+	if toolingErr != nil {
+		runtime.LogErrorf(ctx, "failed to initialize board: %v", toolingErr)
 		// TODO: Display error to user?
 	}
 
@@ -25,7 +41,7 @@ func (a *App) Startup(ctx context.Context) {
 			runtime.LogErrorf(ctx, "failed to get SBC board: %v", err)
 			return
 		}
-		a.selectedBoard = b
+		a.sessions.Attach(b)
 	} else {
 		u, err := update.NewUpdater(a.version, os.Getenv("UPDATE_URL"))
 		if err != nil {
@@ -33,38 +49,361 @@ func (a *App) Startup(ctx context.Context) {
 		} else {
 			a.updater = u
 		}
+
+		if toolingErr == nil {
+			a.watchBoards(ctx)
+		}
 	}
 }
 
 func (a *App) Shutdown(ctx context.Context) {
-	a.selectedBoard.CloseTunnels(ctx)
+	if a.stopWatcher != nil {
+		a.stopWatcher()
+	}
+	if a.stopStats != nil {
+		a.stopStats()
+	}
+	if err := a.appProxy.Close(); err != nil {
+		runtime.LogErrorf(ctx, "failed to close app proxy: %v", err)
+	}
+	a.monitors.StopAll()
+	a.sessions.Close(ctx)
 }
 
 func (a *App) ctx() context.Context {
 	return a.ctxHolder.Get()
 }
 
+// session returns the session of the selected board, board operations should use its
+// context so that they are cancelled when the board is switched.
+func (a *App) session() *session.Session {
+	return a.sessions.Active()
+}
+
+// sessionFor returns the session of the connected board with the given id, or the
+// session of the selected board if id is empty.
+func (a *App) sessionFor(boardID string) (*session.Session, error) {
+	return a.sessions.Get(boardID)
+}
+
+// resolveBoard returns the connected board with the given id for the app proxy.
+func (a *App) resolveBoard(boardID string) (context.Context, *board.Board, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.Context(), s.Board, nil
+}
+
+func (a *App) onSessionEvent(e session.Event) {
+	if e.Type == session.StateChanged {
+		runtime.EventsEmit(a.ctx(), "board-connection-onchange", e)
+		return
+	}
+	runtime.EventsEmit(a.ctx(), "board-session-onchange", e)
+}
+
+// onTerminalEvent streams the embedded terminals to the frontend, the output with
+// "terminal-output" and their end with "terminal-exit".
+func (a *App) onTerminalEvent(e terminal.Event) {
+	if e.Type == terminal.ShellOutput {
+		runtime.EventsEmit(a.ctx(), "terminal-output", e)
+		return
+	}
+	runtime.EventsEmit(a.ctx(), "terminal-exit", e)
+}
+
+// onMonitorEvent streams the resource samples of the monitored boards with the
+// "resource-sample" event.
+func (a *App) onMonitorEvent(e monitor.Event) {
+	runtime.EventsEmit(a.ctx(), "resource-sample", e)
+}
+
+func (a *App) exportTerminalRecording(name string, dest string) (string, error) {
+	if dest == "" {
+		var err error
+		dest, err = runtime.SaveFileDialog(a.ctx(), runtime.SaveDialogOptions{
+			DefaultFilename: name,
+			Filters:         []runtime.FileFilter{{DisplayName: "Asciicast (*.cast)", Pattern: "*.cast"}},
+		})
+		if err != nil || dest == "" {
+			return "", err
+		}
+	}
+	if err := terminal.ExportRecording(name, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// collectDiagnostics writes the diagnostic bundle of the board, or only of the app
+// if no board is connected, to dest, asking for it if empty.
+func (a *App) collectDiagnostics(boardID string, dest string) (string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return "", err
+	}
+	b := s.Board
+	if !slices.Contains(a.sessions.Boards(), b) {
+		b = nil
+	}
+
+	if dest == "" {
+		dest, err = runtime.SaveFileDialog(a.ctx(), runtime.SaveDialogOptions{
+			DefaultFilename: diagnostics.FileName(b),
+			Filters:         zipFilters,
+		})
+		if err != nil || dest == "" {
+			return "", err
+		}
+	}
+	if err := diagnostics.Collect(s.Context(), b, a.version, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// BackupResult is the result of CreateBackup, Path is empty if the dialog was cancelled.
+type BackupResult struct {
+	Path     string          `json:"path"`
+	Manifest backup.Manifest `json:"manifest"`
+}
+
+var zipFilters = []runtime.FileFilter{{DisplayName: "Zip (*.zip)", Pattern: "*.zip"}}
+
+func (a *App) createBackup(boardID string, dest string) (backup.Manifest, string, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return backup.Manifest{}, "", err
+	}
+	if dest == "" {
+		dest, err = runtime.SaveFileDialog(a.ctx(), runtime.SaveDialogOptions{
+			DefaultFilename: backup.FileName(s.Board),
+			Filters:         zipFilters,
+		})
+		if err != nil || dest == "" {
+			return backup.Manifest{}, "", err
+		}
+	}
+	manifest, err := backup.Create(s.Context(), s.Board, a.version, dest)
+	if err != nil {
+		return backup.Manifest{}, "", err
+	}
+	return manifest, dest, nil
+}
+
+func (a *App) restoreBackup(boardID string, src string, dryRun bool, overwriteSSH bool) (backup.Plan, error) {
+	s, err := a.sessionFor(boardID)
+	if err != nil {
+		return backup.Plan{}, err
+	}
+	if src == "" {
+		src, err = runtime.OpenFileDialog(a.ctx(), runtime.OpenDialogOptions{Filters: zipFilters})
+		if err != nil || src == "" {
+			return backup.Plan{}, err
+		}
+	}
+	return backup.Restore(s.Context(), s.Board, src, dryRun, overwriteSSH)
+}
+
+// CommandOutputEvent is the payload of the "command-output" event, Data is base64
+// encoded since the output may be binary.
+type CommandOutputEvent struct {
+	ID      string             `json:"id"`
+	BoardID string             `json:"boardId"`
+	Stream  board.OutputStream `json:"stream"`
+	Data    string             `json:"data"`
+}
+
+// CommandExitEvent is the payload of the "command-exit" event.
+type CommandExitEvent struct {
+	ID       string `json:"id"`
+	BoardID  string `json:"boardId"`
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+}
+
+// startCommand runs the command in the background, streaming its output with the
+// "command-output" events until the "command-exit" one.
+func (a *App) startCommand(s *session.Session, name string, args []string) string {
+	ctx, cancel := context.WithCancel(s.Context())
+
+	a.commandsMu.Lock()
+	a.lastCommandID++
+	id := fmt.Sprintf("cmd-%d", a.lastCommandID)
+	a.commands[id] = cancel
+	a.commandsMu.Unlock()
+
+	boardID := s.Board.Id
+	go func() {
+		defer func() {
+			a.commandsMu.Lock()
+			delete(a.commands, id)
+			a.commandsMu.Unlock()
+			cancel()
+		}()
+
+		code, err := s.Board.StreamCommand(ctx, name, args, func(stream board.OutputStream, data []byte) {
+			runtime.EventsEmit(a.ctx(), "command-output", CommandOutputEvent{
+				ID:      id,
+				BoardID: boardID,
+				Stream:  stream,
+				Data:    base64.StdEncoding.EncodeToString(data),
+			})
+		})
+		e := CommandExitEvent{ID: id, BoardID: boardID, ExitCode: code}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		runtime.EventsEmit(a.ctx(), "command-exit", e)
+	}()
+	return id
+}
+
+func (a *App) cancelCommand(id string) error {
+	a.commandsMu.Lock()
+	cancel, ok := a.commands[id]
+	a.commandsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("command %s not found", id)
+	}
+	cancel()
+	return nil
+}
+
+// watchBoards keeps detectedBoards in sync with the board discoveries and notifies the
+// frontend on every change, so that it does not need to poll GetBoardList.
+func (a *App) watchBoards(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	a.stopWatcher = cancel
+	a.watcher = board.NewWatcher(func(e board.Event) {
+		boards, _ := a.watcher.Boards()
+		a.setDetectedBoards(board.Enrich(boards))
+		runtime.EventsEmit(ctx, "board-list-onchange", e)
+	})
+
+	go a.watcher.Watch(ctx)
+}
+
+// TunnelStatsEvent is the payload of the "tunnel-stats" event.
+type TunnelStatsEvent struct {
+	BoardID string         `json:"boardId"`
+	Stats   []tunnel.Stats `json:"stats"`
+}
+
+// reportTunnelStats periodically emits the tunnel statistics of the connected boards,
+// so that the frontend can show the live traffic without polling GetTunnelStats.
+func (a *App) reportTunnelStats(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	a.stopStats = cancel
+
+	go func() {
+		ticker := time.NewTicker(tunnelStatsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			for _, b := range a.sessions.Boards() {
+				if stats := b.TunnelStats(); len(stats) > 0 {
+					runtime.EventsEmit(ctx, "tunnel-stats", TunnelStatsEvent{BoardID: b.Id, Stats: stats})
+				}
+			}
+		}
+	}()
+}
+
+func (a *App) setDetectedBoards(boards []*board.Board) {
+	a.boardsMu.Lock()
+	defer a.boardsMu.Unlock()
+	a.detectedBoards = boards
+}
+
+func (a *App) findDetectedBoard(id string) *board.Board {
+	a.boardsMu.Lock()
+	defer a.boardsMu.Unlock()
+	for _, b := range a.detectedBoards {
+		if b.Id == id {
+			return b
+		}
+	}
+	return nil
+}
+
 func (a *App) detectBoards() ([]*board.Board, error) {
+	if a.watcher != nil {
+		if boards, ok := a.watcher.Boards(); ok {
+			boards = board.Enrich(boards)
+			a.setDetectedBoards(boards)
+			return boards, nil
+		}
+	}
+
 	boards, err := board.GetBoards(a.ctx())
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect boards: %w", err)
 	}
-	a.detectedBoards = boards
+	a.setDetectedBoards(boards)
 	return boards, nil
 }
 
+// findBoard detects the boards again and returns the one with the given id, or nil.
+func (a *App) findBoard(id string) *board.Board {
+	if _, err := a.detectBoards(); err != nil {
+		return nil
+	}
+	return a.findDetectedBoard(id)
+}
+
 func (a *App) selectBoard(id string, password string) error {
-	for _, b := range a.detectedBoards {
-		if b.Id == id {
-			if err := b.EstablishConnection(a.ctx(), password); err != nil {
-				return fmt.Errorf("failed to select board: %w", err)
-			}
-			// Important: this is safe because selectedBoard is initialized to a Noop board and changed once,
-			// if one day we need to change it multiple times we need to gracefully close the previous board live fields
-			// (e.g. Conn, tunnels)
-			*a.selectedBoard = *b
-			return nil
-		}
+	b := a.findDetectedBoard(id)
+	if b == nil {
+		return fmt.Errorf("failed to select board: board with id %s not found", id)
+	}
+	if err := a.sessions.Switch(b, password); err != nil {
+		return fmt.Errorf("failed to select board: %w", err)
+	}
+	return nil
+}
+
+func (a *App) connectBoard(id string, password string) error {
+	b := a.findDetectedBoard(id)
+	if b == nil {
+		return fmt.Errorf("failed to connect board: board with id %s not found", id)
+	}
+	if err := a.sessions.Connect(b, password); err != nil {
+		return fmt.Errorf("failed to connect board: %w", err)
+	}
+	return nil
+}
+
+func (a *App) addManualBoard(address string, port int, name string, password string) (*board.Board, error) {
+	b, err := board.AddManualBoard(a.ctx(), address, port, name, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add board: %w", err)
+	}
+	a.onManualBoardsChange(board.Event{Type: board.BoardAdded, Board: b})
+	return b, nil
+}
+
+func (a *App) removeManualBoard(id string) error {
+	b := a.findDetectedBoard(id)
+	if err := board.RemoveManualBoard(id); err != nil {
+		return fmt.Errorf("failed to remove board: %w", err)
+	}
+	if b != nil {
+		a.onManualBoardsChange(board.Event{Type: board.BoardRemoved, Board: b})
+	}
+	return nil
+}
+
+// onManualBoardsChange refreshes the detected boards and notifies the frontend like
+// the board watcher does.
+func (a *App) onManualBoardsChange(e board.Event) {
+	if _, err := a.detectBoards(); err != nil {
+		runtime.LogErrorf(a.ctx(), "failed to refresh boards: %v", err)
 	}
-	return fmt.Errorf("failed to select board: board with id %s not found", id)
+	runtime.EventsEmit(a.ctx(), "board-list-onchange", e)
 }
@@ -0,0 +1,79 @@
+package applog
+
+// The app logs go to stderr and to a file of the config dir, so that they can be
+// attached to the diagnostic bundles.
+
+import (
+	"app-lab-desktop/internal/config"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const (
+	logsDir = "logs"
+	logFile = "app-lab.log"
+	// maxLogSize is the size above which the log is rotated at startup, the previous
+	// one is kept with the .1 suffix.
+	maxLogSize = 5 * 1024 * 1024
+)
+
+// Setup makes the default slog logger write to stderr and to the log file.
+func Setup() (io.Closer, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	if st, err := os.Stat(p); err == nil && st.Size() > maxLogSize {
+		_ = os.Rename(p, p+".1")
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.MultiWriter(os.Stderr, f), nil)))
+	return f, nil
+}
+
+// Files returns the existing log files, the current one first.
+func Files() ([]string, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range []string{p, p + ".1"} {
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func path() (string, error) {
+	dir, err := config.Path(logsDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create logs dir: %w", err)
+	}
+	return filepath.Join(dir, logFile), nil
+}
+
+// WailsLogger sends the logs of the Wails runtime to slog.
+type WailsLogger struct{}
+
+func (WailsLogger) Print(message string)   { slog.Info(message) }
+func (WailsLogger) Trace(message string)   { slog.Debug(message) }
+func (WailsLogger) Debug(message string)   { slog.Debug(message) }
+func (WailsLogger) Info(message string)    { slog.Info(message) }
+func (WailsLogger) Warning(message string) { slog.Warn(message) }
+func (WailsLogger) Error(message string)   { slog.Error(message) }
+
+func (WailsLogger) Fatal(message string) {
+	slog.Error(message)
+	os.Exit(1)
+}
@@ -123,7 +123,8 @@ func waitUntilHTML(host string, port int, timeout, interval, perTry time.Duratio
 	}
 }
 
-func openUIWhenReady(ctx context.Context, host string, port int, timeout time.Duration) error {
+// waitUIReady waits for the app UI listening on host:port to serve HTML.
+func waitUIReady(host string, port int, timeout time.Duration) error {
 	start := time.Now()
 	// TCP up
 	if err := waitTCPPort(host, port, timeout, 200*time.Millisecond, 400*time.Millisecond); err != nil {
@@ -152,12 +153,12 @@ func openUIWhenReady(ctx context.Context, host string, port int, timeout time.Du
 	if err != nil {
 		return fmt.Errorf("failed to get HTML from http://%s:%d/: %w", host, port, err)
 	}
-
-	runtime.BrowserOpenURL(ctx, fmt.Sprintf("http://%s:%d/", host, port))
 	return nil
 }
 
-func OpenUIWhenReady(ctx context.Context, board *board.Board, targetBoardPort int) error {
+// appEndpoint returns the host and port where the app UI listening on targetBoardPort
+// is reachable from this computer.
+func appEndpoint(ctx context.Context, board *board.Board, targetBoardPort int) (string, int, error) {
 	host := "localhost"
 	port := targetBoardPort
 
@@ -166,16 +167,28 @@ func OpenUIWhenReady(ctx context.Context, board *board.Board, targetBoardPort in
 		host = board.Info.Address
 	} else if board.Info.Protocol != apiBoard.LocalProtocol {
 		// otherwise, forward the port through the tunnel
-		t, err := board.StartTunnel(ctx, board.Conn, strconv.Itoa(targetBoardPort), targetBoardPort)
+		t, err := board.StartTunnel(ctx, board.Conn(), strconv.Itoa(targetBoardPort), targetBoardPort)
 		if err != nil {
-			return fmt.Errorf("failed to forward port %d: %w", targetBoardPort, err)
+			return "", 0, fmt.Errorf("failed to forward port %d: %w", targetBoardPort, err)
 		}
 		p, err := t.Port()
 		if err != nil {
-			return fmt.Errorf("failed to get forwarded port for port %d: %w", targetBoardPort, err)
+			return "", 0, fmt.Errorf("failed to get forwarded port for port %d: %w", targetBoardPort, err)
 		}
 		port = p
 	}
+	return host, port, nil
+}
 
-	return openUIWhenReady(ctx, host, port, uiOpeningTimeout)
+func OpenUIWhenReady(ctx context.Context, board *board.Board, targetBoardPort int) error {
+	host, port, err := appEndpoint(ctx, board, targetBoardPort)
+	if err != nil {
+		return err
+	}
+	if err := waitUIReady(host, port, uiOpeningTimeout); err != nil {
+		return err
+	}
+
+	runtime.BrowserOpenURL(ctx, fmt.Sprintf("http://%s:%d/", host, port))
+	return nil
 }
@@ -0,0 +1,237 @@
+package appui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"app-lab-desktop/internal/board"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// DefaultProxyPort is the port of the app UIs proxy, fixed so that their URLs
+	// survive a restart of the app.
+	DefaultProxyPort = 38800
+	proxyPortEnv     = "ARDUINO_APP_LAB_PROXY_PORT"
+)
+
+// Resolver returns the board with the given id, the active one if id is empty, and
+// the context of its session.
+type Resolver func(boardID string) (context.Context, *board.Board, error)
+
+// Proxy serves the UIs of the apps running on the boards under a single origin:
+// /apps/<port>/ for the active board and /boards/<id>/apps/<port>/ for a given one.
+type Proxy struct {
+	resolve  Resolver
+	listener net.Listener
+	server   *http.Server
+}
+
+func NewProxy(resolve Resolver) *Proxy {
+	return &Proxy{resolve: resolve}
+}
+
+// Start listens on the port set with ARDUINO_APP_LAB_PROXY_PORT, or DefaultProxyPort.
+// If it is in use, an available port is used instead and the URLs are not stable.
+func (p *Proxy) Start() error {
+	port := DefaultProxyPort
+	if v := os.Getenv(proxyPortEnv); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", proxyPortEnv, v, err)
+		}
+		port = n
+	}
+
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		// The port in use error differs between the platforms, e.g. WSAEADDRINUSE on
+		// Windows, any failure falls back.
+		slog.Warn("app proxy port not available, using an available one", "port", port, "err", err)
+		l, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to listen for the app proxy: %w", err)
+	}
+
+	p.listener = l
+	p.server = &http.Server{Handler: http.HandlerFunc(p.serveHTTP)}
+	go func() {
+		if err := p.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("app proxy stopped", "err", err)
+		}
+	}()
+	return nil
+}
+
+func (p *Proxy) Close() error {
+	if p.server == nil {
+		return nil
+	}
+	return p.server.Close()
+}
+
+// URL returns the URL of the app UI listening on boardPort.
+func (p *Proxy) URL(boardID string, boardPort int) (string, error) {
+	if p.listener == nil {
+		return "", fmt.Errorf("app proxy is not running")
+	}
+	return fmt.Sprintf("http://localhost:%d%s", p.listener.Addr().(*net.TCPAddr).Port, appPrefix(boardID, strconv.Itoa(boardPort))), nil
+}
+
+// OpenUIWhenReady opens in the browser the proxied URL of the app UI listening on
+// targetBoardPort, once it serves HTML.
+func (p *Proxy) OpenUIWhenReady(ctx context.Context, boardID string, b *board.Board, targetBoardPort int) error {
+	u, err := p.URL(boardID, targetBoardPort)
+	if err != nil {
+		return err
+	}
+	host, port, err := appEndpoint(ctx, b, targetBoardPort)
+	if err != nil {
+		return err
+	}
+	if err := waitUIReady(host, port, uiOpeningTimeout); err != nil {
+		return err
+	}
+
+	runtime.BrowserOpenURL(ctx, u)
+	return nil
+}
+
+func (p *Proxy) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// A site whose name resolves to 127.0.0.1 would otherwise reach the app UIs from
+	// the browser (DNS rebinding).
+	if !validHost(r.Host, p.listener.Addr().(*net.TCPAddr).Port) {
+		http.Error(w, fmt.Sprintf("invalid host %q", r.Host), http.StatusForbidden)
+		return
+	}
+
+	boardID, port, prefix, ok := parseAppPath(r.URL.Path)
+	if ok && r.URL.Path == strings.TrimSuffix(prefix, "/") {
+		// Relative URLs of the app pages need the trailing slash.
+		http.Redirect(w, r, prefix, http.StatusMovedPermanently)
+		return
+	}
+
+	// Pages requesting absolute paths, e.g. /static/app.js, lose the prefix: they are
+	// routed to the app of the referring page.
+	if !ok {
+		prefix = ""
+		if ref, err := url.Parse(r.Referer()); err == nil {
+			boardID, port, _, ok = parseAppPath(ref.Path)
+		}
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	boardPort, err := strconv.Atoi(port)
+	if err != nil || boardPort <= 0 || boardPort > 65535 {
+		http.Error(w, fmt.Sprintf("invalid port %q", port), http.StatusBadRequest)
+		return
+	}
+	ctx, b, err := p.resolve(boardID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	host, hostPort, err := appEndpoint(ctx, b, boardPort)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	target := &url.URL{Scheme: "http", Host: net.JoinHostPort(host, strconv.Itoa(hostPort))}
+	appPath := appPrefix(boardID, port)
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if prefix != "" {
+				pr.Out.URL.Path = "/" + strings.TrimPrefix(pr.In.URL.Path, prefix)
+				pr.Out.URL.RawPath = ""
+			}
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			pr.Out.Header.Set("X-Forwarded-Prefix", strings.TrimSuffix(appPath, "/"))
+		},
+		// Server-sent events must reach the browser as soon as they are written.
+		FlushInterval: -1,
+		ModifyResponse: func(resp *http.Response) error {
+			if loc := resp.Header.Get("Location"); loc != "" {
+				resp.Header.Set("Location", rewriteLocation(loc, target.Host, appPath))
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.Warn("app proxy request failed", "port", boardPort, "err", err)
+			http.Error(w, fmt.Sprintf("app on port %d is not reachable: %v", boardPort, err), http.StatusBadGateway)
+		},
+	}
+	rp.ServeHTTP(w, r)
+}
+
+// validHost tells whether the Host header of a request names the proxy listening on
+// port, by the names of its URLs.
+func validHost(host string, port int) bool {
+	p := strconv.Itoa(port)
+	return host == "localhost:"+p || host == "127.0.0.1:"+p
+}
+
+func appPrefix(boardID string, port string) string {
+	if boardID == "" {
+		return "/apps/" + port + "/"
+	}
+	return "/boards/" + boardID + "/apps/" + port + "/"
+}
+
+// parseAppPath parses /apps/<port>/... and /boards/<id>/apps/<port>/..., returning
+// the prefix to strip before forwarding the request to the app.
+func parseAppPath(p string) (boardID, port, prefix string, ok bool) {
+	rest := p
+	if after, found := strings.CutPrefix(rest, "/boards/"); found {
+		boardID, rest, found = strings.Cut(after, "/")
+		if !found || boardID == "" {
+			return "", "", "", false
+		}
+		rest = "/" + rest
+	}
+	rest, found := strings.CutPrefix(rest, "/apps/")
+	if !found {
+		return "", "", "", false
+	}
+	port, _, _ = strings.Cut(rest, "/")
+	if port == "" {
+		return "", "", "", false
+	}
+	return boardID, port, appPrefix(boardID, port), true
+}
+
+// rewriteLocation makes the redirects of the app, to an absolute path or to its own
+// address, stay behind the proxy.
+func rewriteLocation(loc string, targetHost string, prefix string) string {
+	u, err := url.Parse(loc)
+	if err != nil {
+		return loc
+	}
+	if u.Host != "" && u.Host != targetHost {
+		return loc
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		return loc
+	}
+	u.Scheme = ""
+	u.Host = ""
+	u.Path = strings.TrimSuffix(prefix, "/") + u.Path
+	u.RawPath = ""
+	return u.String()
+}
@@ -0,0 +1,66 @@
+package appui
+
+import "testing"
+
+func TestParseAppPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		boardID string
+		port    string
+		prefix  string
+		ok      bool
+	}{
+		{path: "/apps/7000/", port: "7000", prefix: "/apps/7000/", ok: true},
+		{path: "/apps/7000", port: "7000", prefix: "/apps/7000/", ok: true},
+		{path: "/apps/7000/static/app.js", port: "7000", prefix: "/apps/7000/", ok: true},
+		{path: "/boards/abc/apps/7000/index.html", boardID: "abc", port: "7000", prefix: "/boards/abc/apps/7000/", ok: true},
+		{path: "/apps/", ok: false},
+		{path: "/boards/abc/", ok: false},
+		{path: "/static/app.js", ok: false},
+	}
+
+	for _, tt := range tests {
+		boardID, port, prefix, ok := parseAppPath(tt.path)
+		if boardID != tt.boardID || port != tt.port || prefix != tt.prefix || ok != tt.ok {
+			t.Errorf("parseAppPath(%q) = %q, %q, %q, %v, expected %q, %q, %q, %v",
+				tt.path, boardID, port, prefix, ok, tt.boardID, tt.port, tt.prefix, tt.ok)
+		}
+	}
+}
+
+func TestRewriteLocation(t *testing.T) {
+	tests := []struct {
+		loc      string
+		expected string
+	}{
+		{loc: "/login?next=/", expected: "/apps/7000/login?next=/"},
+		{loc: "http://127.0.0.1:41234/login", expected: "/apps/7000/login"},
+		{loc: "login", expected: "login"},
+		{loc: "https://example.com/login", expected: "https://example.com/login"},
+	}
+
+	for _, tt := range tests {
+		if got := rewriteLocation(tt.loc, "127.0.0.1:41234", "/apps/7000/"); got != tt.expected {
+			t.Errorf("rewriteLocation(%q) = %q, expected %q", tt.loc, got, tt.expected)
+		}
+	}
+}
+
+func TestValidHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "localhost:38800", expected: true},
+		{host: "127.0.0.1:38800", expected: true},
+		{host: "localhost:8080", expected: false},
+		{host: "localhost", expected: false},
+		{host: "attacker.example:38800", expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := validHost(tt.host, 38800); got != tt.expected {
+			t.Errorf("validHost(%q) = %v, expected %v", tt.host, got, tt.expected)
+		}
+	}
+}
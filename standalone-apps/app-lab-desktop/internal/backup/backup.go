@@ -0,0 +1,267 @@
+package backup
+
+// A backup is a zip with a versioned manifest, the board settings, its Wi-Fi
+// profiles and SSH files, and the user apps:
+//
+//	manifest.json
+//	settings.json
+//	wifi.json
+//	ssh/<file>
+//	apps/<app>/...
+//
+// It holds the Wi-Fi secrets and the SSH private keys, and is written readable by
+// the user only. The entries of the board files keep their permissions.
+
+import (
+	"app-lab-desktop/internal/board"
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatVersion is the version of the backup layout, bumped on incompatible changes.
+	FormatVersion = 1
+	formatName    = "arduino-app-lab-backup"
+
+	appsDir = "/home/arduino/ArduinoApps"
+	sshDir  = "/home/arduino/.ssh"
+
+	manifestEntry = "manifest.json"
+	settingsEntry = "settings.json"
+	wifiEntry     = "wifi.json"
+	sshPrefix     = "ssh/"
+	appsPrefix    = "apps/"
+
+	// creatorUnix is the zip creator of the entries whose external attributes hold
+	// the Unix permissions.
+	creatorUnix = 3
+)
+
+// skippedDirs are not backed up, they are rebuilt by the apps.
+var skippedDirs = []string{".cache", "__pycache__", ".venv", "node_modules"}
+
+type Manifest struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"createdAt"`
+	AppVersion string    `json:"appVersion"`
+	BoardID    string    `json:"boardId"`
+	BoardName  string    `json:"boardName"`
+	// Warnings lists what could not be backed up.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Settings are the board settings restored with the board helpers.
+type Settings struct {
+	Name           string `json:"name"`
+	KeyboardLayout string `json:"keyboardLayout"`
+	Timezone       string `json:"timezone,omitempty"`
+	Locale         string `json:"locale,omitempty"`
+}
+
+// FileName returns the default name of a backup of b created now.
+func FileName(b *board.Board) string {
+	id := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\: `, r) {
+			return '_'
+		}
+		return r
+	}, b.Id)
+	return "app-lab-backup-" + id + "-" + time.Now().Format("20060102-150405") + ".zip"
+}
+
+// Create backs up the board to dest and returns the manifest of the backup. The
+// settings and the apps are required, the Wi-Fi profiles and the SSH files that
+// cannot be read are reported in the manifest warnings.
+func Create(ctx context.Context, b *board.Board, appVersion string, dest string) (Manifest, error) {
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to create backup: %w", err)
+	}
+	w := &writer{zip: zip.NewWriter(f), now: time.Now(), modes: map[string]fs.FileMode{}}
+
+	manifest, err := w.write(ctx, b, appVersion)
+	if err == nil {
+		err = w.zip.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dest)
+		return Manifest{}, fmt.Errorf("failed to create backup: %w", err)
+	}
+	return manifest, nil
+}
+
+type writer struct {
+	zip *zip.Writer
+	now time.Time
+	// modes are the permissions of the board files by path, List not telling them.
+	modes map[string]fs.FileMode
+}
+
+func (w *writer) write(ctx context.Context, b *board.Board, appVersion string) (Manifest, error) {
+	m := Manifest{
+		Format:     formatName,
+		Version:    FormatVersion,
+		CreatedAt:  w.now,
+		AppVersion: appVersion,
+		BoardID:    b.Id,
+	}
+
+	var s Settings
+	var err error
+	if s.Name, err = b.GetName(ctx); err != nil {
+		return m, fmt.Errorf("failed to get board name: %w", err)
+	}
+	if s.KeyboardLayout, err = b.GetKeyboardLayout(ctx); err != nil {
+		return m, fmt.Errorf("failed to get keyboard layout: %w", err)
+	}
+	if s.Timezone, err = b.GetTimezone(ctx); err != nil {
+		m.Warnings = append(m.Warnings, err.Error())
+	}
+	if s.Locale, err = b.GetLocale(ctx); err != nil {
+		m.Warnings = append(m.Warnings, err.Error())
+	}
+	m.BoardName = s.Name
+	if err := w.addJSON(settingsEntry, s); err != nil {
+		return m, err
+	}
+
+	profiles, err := b.ListWiFiProfiles(ctx)
+	if err != nil {
+		m.Warnings = append(m.Warnings, err.Error())
+		profiles = []board.WiFiProfile{}
+	}
+	for _, p := range profiles {
+		if p.KeyMgmt != "" && p.PSK == "" {
+			m.Warnings = append(m.Warnings, fmt.Sprintf("the secret of the Wi-Fi profile %s is not readable, it will not be restored", p.Name))
+		}
+	}
+	if err := w.addJSON(wifiEntry, profiles); err != nil {
+		return m, err
+	}
+
+	for _, dir := range []string{sshDir, appsDir} {
+		if err := w.readModes(ctx, b, dir); err != nil {
+			m.Warnings = append(m.Warnings, fmt.Sprintf("failed to read the permissions of the files under %s, they will not be restored: %v", dir, err))
+		}
+	}
+	if err := w.addTree(ctx, b, sshDir, sshPrefix); err != nil {
+		m.Warnings = append(m.Warnings, fmt.Sprintf("failed to back up the SSH files: %v", err))
+	}
+	if err := w.addTree(ctx, b, appsDir, appsPrefix); err != nil {
+		return m, fmt.Errorf("failed to back up the apps: %w", err)
+	}
+
+	return m, w.addJSON(manifestEntry, m)
+}
+
+func (w *writer) addJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	out, err := w.create(name, 0)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// create adds the entry name, with the permissions mode if not zero.
+func (w *writer) create(name string, mode fs.FileMode) (io.Writer, error) {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: w.now}
+	if mode != 0 {
+		header.SetMode(mode)
+	}
+	return w.zip.CreateHeader(header)
+}
+
+// readModes reads the permissions of the files under dir, e.g. the executable bit
+// of the app scripts.
+func (w *writer) readModes(ctx context.Context, b *board.Board, dir string) error {
+	res, err := b.RunCommand(ctx, "find", []string{dir, "-type", "f", "-printf", `%m %p\0`})
+	if err != nil {
+		return err
+	}
+	// find fails on the unreadable directories, the modes of the others are kept.
+	found := 0
+	for _, line := range strings.Split(res.Stdout, "\x00") {
+		mode, p, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			continue
+		}
+		w.modes[p] = fs.FileMode(perm).Perm()
+		found++
+	}
+	if res.ExitCode != 0 && found == 0 {
+		return errors.New(strings.TrimSpace(res.Stderr))
+	}
+	return nil
+}
+
+// addTree adds the files under dir, walked with List, to the entries under prefix.
+func (w *writer) addTree(ctx context.Context, b *board.Board, dir string, prefix string) error {
+	entries, err := b.Conn().List(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if e.Name == "." || e.Name == ".." {
+			continue
+		}
+		p := path.Join(dir, e.Name)
+		if e.IsDir {
+			if slices.Contains(skippedDirs, e.Name) {
+				continue
+			}
+			if err := w.addTree(ctx, b, p, prefix+e.Name+"/"); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.addFile(b, p, prefix+e.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *writer) addFile(b *board.Board, src string, name string) error {
+	r, err := b.Conn().ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	defer r.Close()
+	out, err := w.create(name, w.modes[src])
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	slog.Debug("backed up file", "path", src)
+	return nil
+}
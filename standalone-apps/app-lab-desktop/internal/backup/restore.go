@@ -0,0 +1,379 @@
+package backup
+
+import (
+	"app-lab-desktop/internal/board"
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+type Operation string
+
+const (
+	OpCreate    Operation = "create"
+	OpOverwrite Operation = "overwrite"
+	// OpMerge adds the missing lines of the backed up authorized_keys or known_hosts
+	// to the board one.
+	OpMerge Operation = "merge"
+	OpSet   Operation = "set"
+	OpSkip  Operation = "skip"
+)
+
+type ActionKind string
+
+const (
+	SettingAction ActionKind = "setting"
+	WiFiAction    ActionKind = "wifi"
+	SSHAction     ActionKind = "ssh"
+	AppAction     ActionKind = "app"
+)
+
+// Action is a step of a restore, Error is set if it failed.
+type Action struct {
+	Kind      ActionKind `json:"kind"`
+	Target    string     `json:"target"`
+	Operation Operation  `json:"operation"`
+	Detail    string     `json:"detail,omitempty"`
+	Error     string     `json:"error,omitempty"`
+
+	apply func(ctx context.Context) error
+}
+
+// Plan lists the actions of a restore, performed or, on dry runs, to perform.
+type Plan struct {
+	Source   string   `json:"source"`
+	Manifest Manifest `json:"manifest"`
+	DryRun   bool     `json:"dryRun"`
+	Actions  []Action `json:"actions"`
+}
+
+// Restore pushes the backup src onto the board: the settings, the missing Wi-Fi
+// profiles, the SSH files and the apps. The SSH files already on the board, e.g. its
+// private keys or config, are only replaced if overwriteSSH is set, the authorized
+// keys and known hosts being merged. On dry runs, the board is left untouched and
+// the plan tells what would be done.
+func Restore(ctx context.Context, b *board.Board, src string, dryRun bool, overwriteSSH bool) (Plan, error) {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return Plan{}, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer r.Close()
+
+	plan := Plan{Source: src, DryRun: dryRun}
+	if err := readJSON(&r.Reader, manifestEntry, &plan.Manifest); err != nil {
+		return Plan{}, err
+	}
+	if plan.Manifest.Format != formatName {
+		return Plan{}, fmt.Errorf("%s is not an App Lab backup", src)
+	}
+	if plan.Manifest.Version < 1 || plan.Manifest.Version > FormatVersion {
+		return Plan{}, fmt.Errorf("unsupported backup version %d, update the app", plan.Manifest.Version)
+	}
+
+	p := &planner{b: b, overwriteSSH: overwriteSSH}
+	if err := p.planSettings(ctx, &r.Reader); err != nil {
+		return Plan{}, err
+	}
+	if err := p.planWiFi(ctx, &r.Reader); err != nil {
+		return Plan{}, err
+	}
+	if err := p.planFiles(ctx, &r.Reader); err != nil {
+		return Plan{}, err
+	}
+	plan.Actions = p.actions
+	if dryRun {
+		return plan, nil
+	}
+
+	failed := 0
+	for i := range plan.Actions {
+		a := &plan.Actions[i]
+		if a.apply == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return plan, err
+		}
+		if err := a.apply(ctx); err != nil {
+			a.Error = err.Error()
+			failed++
+		}
+	}
+	if failed > 0 {
+		return plan, fmt.Errorf("%d of the %d restore actions failed", failed, len(plan.Actions))
+	}
+	return plan, nil
+}
+
+type planner struct {
+	b            *board.Board
+	overwriteSSH bool
+	actions      []Action
+}
+
+func (p *planner) add(a Action) {
+	p.actions = append(p.actions, a)
+}
+
+func (p *planner) planSettings(ctx context.Context, r *zip.Reader) error {
+	var s Settings
+	if err := readJSON(r, settingsEntry, &s); err != nil {
+		return err
+	}
+
+	settings := []struct {
+		name  string
+		value string
+		get   func(context.Context) (string, error)
+		set   func(context.Context, string) error
+	}{
+		{"name", s.Name, p.b.GetName, p.b.SetName},
+		{"keyboard layout", s.KeyboardLayout, p.b.GetKeyboardLayout, p.b.SetKeyboardLayout},
+		{"timezone", s.Timezone, p.b.GetTimezone, p.b.SetTimezone},
+		{"locale", s.Locale, p.b.GetLocale, p.b.SetLocale},
+	}
+	for _, setting := range settings {
+		if setting.value == "" {
+			continue
+		}
+		current, err := setting.get(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get board %s: %w", setting.name, err)
+		}
+		if current == setting.value {
+			p.add(Action{Kind: SettingAction, Target: setting.name, Operation: OpSkip, Detail: "unchanged"})
+			continue
+		}
+		value, set := setting.value, setting.set
+		p.add(Action{
+			Kind:      SettingAction,
+			Target:    setting.name,
+			Operation: OpSet,
+			Detail:    fmt.Sprintf("%q to %q", current, value),
+			apply:     func(ctx context.Context) error { return set(ctx, value) },
+		})
+	}
+	return nil
+}
+
+func (p *planner) planWiFi(ctx context.Context, r *zip.Reader) error {
+	var profiles []board.WiFiProfile
+	if err := readJSON(r, wifiEntry, &profiles); err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		return nil
+	}
+	current, err := p.b.ListWiFiProfiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, profile := range profiles {
+		a := Action{Kind: WiFiAction, Target: profile.Name, Operation: OpCreate, Detail: "SSID " + profile.SSID}
+		switch {
+		case slices.ContainsFunc(current, func(c board.WiFiProfile) bool { return c.Name == profile.Name }):
+			a.Operation, a.Detail = OpSkip, "already saved on the board"
+		case profile.KeyMgmt != "" && profile.PSK == "":
+			a.Operation, a.Detail = OpSkip, "the secret is not in the backup"
+		default:
+			a.apply = func(ctx context.Context) error { return p.b.AddWiFiProfile(ctx, profile) }
+		}
+		p.add(a)
+	}
+	return nil
+}
+
+func (p *planner) planFiles(ctx context.Context, r *zip.Reader) error {
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || f.Name == manifestEntry || f.Name == settingsEntry || f.Name == wifiEntry {
+			continue
+		}
+		kind, target, err := entryTarget(f.Name)
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		a := Action{Kind: kind, Target: target, Operation: OpCreate}
+		if _, err := p.b.Conn().Stats(target); err == nil {
+			a.Operation = OpOverwrite
+		}
+
+		if kind == SSHAction && a.Operation == OpOverwrite {
+			switch name := path.Base(target); {
+			case name == "authorized_keys" || name == "known_hosts":
+				if err := p.planMerge(&a, f); err != nil {
+					return err
+				}
+				p.add(a)
+				continue
+			case !p.overwriteSSH:
+				a.Operation, a.Detail = OpSkip, "already on the board"
+				p.add(a)
+				continue
+			}
+		}
+
+		mode := entryMode(f)
+		a.apply = func(ctx context.Context) error {
+			src, err := f.Open()
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+			return p.writeFile(ctx, kind, target, src, mode)
+		}
+		p.add(a)
+	}
+	return nil
+}
+
+// planMerge merges the keys of the backup with the ones of the board: its authorized
+// keys may hold the key of the app, and its known hosts the ones met since the backup.
+func (p *planner) planMerge(a *Action, f *zip.File) error {
+	backedUp, err := readEntry(f)
+	if err != nil {
+		return err
+	}
+	current, err := p.b.Conn().ReadFile(a.Target)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", a.Target, err)
+	}
+	defer current.Close()
+	currentData, err := io.ReadAll(current)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", a.Target, err)
+	}
+
+	merged, added := mergeKeys(string(currentData), string(backedUp))
+	if added == 0 {
+		a.Operation, a.Detail = OpSkip, "every key is already on the board"
+		return nil
+	}
+	a.Operation, a.Detail = OpMerge, fmt.Sprintf("%d keys to add", added)
+	target := a.Target
+	a.apply = func(ctx context.Context) error {
+		return p.writeFile(ctx, SSHAction, target, strings.NewReader(merged), 0)
+	}
+	return nil
+}
+
+// writeFile writes data to target, with the permissions mode if not zero. The SSH
+// files are always made private.
+func (p *planner) writeFile(ctx context.Context, kind ActionKind, target string, data io.Reader, mode fs.FileMode) error {
+	dir := path.Dir(target)
+	if err := p.b.Conn().MkDirAll(dir); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := p.b.Conn().WriteFile(data, target); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	if kind != SSHAction {
+		if mode == 0 {
+			return nil
+		}
+		res, err := p.b.RunCommand(ctx, "chmod", []string{fmt.Sprintf("%o", mode), target})
+		if err == nil && res.ExitCode != 0 {
+			err = errors.New(strings.TrimSpace(res.Stderr))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set the permissions of %s: %w", target, err)
+		}
+		return nil
+	}
+	// sshd ignores the keys readable by others.
+	res, err := p.b.RunCommand(ctx, "chmod", []string{"700", sshDir})
+	if err == nil && res.ExitCode == 0 {
+		res, err = p.b.RunCommand(ctx, "chmod", []string{"600", target})
+	}
+	if err == nil && res.ExitCode != 0 {
+		err = errors.New(strings.TrimSpace(res.Stderr))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set the permissions of %s: %w", target, err)
+	}
+	return nil
+}
+
+// entryTarget returns the path on the board of a file entry of the backup, refusing
+// the entries that would be written elsewhere.
+func entryTarget(name string) (ActionKind, string, error) {
+	var kind ActionKind
+	var dir, rel string
+	if after, ok := strings.CutPrefix(name, sshPrefix); ok {
+		kind, dir, rel = SSHAction, sshDir, after
+	} else if after, ok := strings.CutPrefix(name, appsPrefix); ok {
+		kind, dir, rel = AppAction, appsDir, after
+	} else {
+		return "", "", fmt.Errorf("invalid backup entry %q", name)
+	}
+	if rel == "" || strings.Contains(rel, `\`) || path.IsAbs(rel) || path.Clean(rel) != rel || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", "", fmt.Errorf("invalid backup entry %q", name)
+	}
+	return kind, path.Join(dir, rel), nil
+}
+
+// mergeKeys appends to current the key lines of backedUp it misses, and returns the
+// number of keys added.
+func mergeKeys(current string, backedUp string) (string, int) {
+	present := map[string]bool{}
+	for _, line := range strings.Split(current, "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+
+	merged := current
+	added := 0
+	for _, line := range strings.Split(backedUp, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || present[line] {
+			continue
+		}
+		if merged != "" && !strings.HasSuffix(merged, "\n") {
+			merged += "\n"
+		}
+		merged += line + "\n"
+		present[line] = true
+		added++
+	}
+	return merged, added
+}
+
+// entryMode returns the permissions of a file entry, zero for the entries of the
+// backups made before they were stored.
+func entryMode(f *zip.File) fs.FileMode {
+	if f.CreatorVersion>>8 != creatorUnix {
+		return 0
+	}
+	return f.Mode().Perm()
+}
+
+func readJSON(r *zip.Reader, name string, v any) error {
+	f, err := r.Open(name)
+	if err != nil {
+		return fmt.Errorf("invalid backup: %w", err)
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("invalid backup %s: %w", name, err)
+	}
+	return nil
+}
+
+func readEntry(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup %s: %w", f.Name, err)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
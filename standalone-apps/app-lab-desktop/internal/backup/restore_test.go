@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"testing"
+)
+
+func TestEntryTarget(t *testing.T) {
+	tests := []struct {
+		name           string
+		expectedKind   ActionKind
+		expectedTarget string
+		expectedErr    bool
+	}{
+		{name: "apps/blink/python/main.py", expectedKind: AppAction, expectedTarget: "/home/arduino/ArduinoApps/blink/python/main.py"},
+		{name: "ssh/authorized_keys", expectedKind: SSHAction, expectedTarget: "/home/arduino/.ssh/authorized_keys"},
+		{name: "apps/../.bashrc", expectedErr: true},
+		{name: "apps/blink/../../../etc/passwd", expectedErr: true},
+		{name: "ssh//etc/passwd", expectedErr: true},
+		{name: "apps/", expectedErr: true},
+		{name: "etc/hostname", expectedErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, target, err := entryTarget(tt.name)
+			if tt.expectedErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if kind != tt.expectedKind || target != tt.expectedTarget {
+				t.Errorf("expected %s %s, got %s %s", tt.expectedKind, tt.expectedTarget, kind, target)
+			}
+		})
+	}
+}
+
+func TestMergeKeys(t *testing.T) {
+	current := "ssh-ed25519 AAAAapp arduino-app-lab"
+	backedUp := "# user keys\nssh-ed25519 AAAAlaptop me@laptop\nssh-ed25519 AAAAapp arduino-app-lab\n\nssh-rsa AAAAci ci\n"
+
+	merged, added := mergeKeys(current, backedUp)
+	expected := "ssh-ed25519 AAAAapp arduino-app-lab\nssh-ed25519 AAAAlaptop me@laptop\nssh-rsa AAAAci ci\n"
+	if merged != expected || added != 2 {
+		t.Errorf("expected %q with 2 keys added, got %q with %d", expected, merged, added)
+	}
+
+	if _, added := mergeKeys(merged, backedUp); added != 0 {
+		t.Errorf("expected no key added, got %d", added)
+	}
+}
+
+func TestEntryMode(t *testing.T) {
+	var buf bytes.Buffer
+	w := &writer{zip: zip.NewWriter(&buf)}
+	for name, mode := range map[string]fs.FileMode{"apps/blink/run.sh": 0755, "apps/blink/app.yaml": 0} {
+		if _, err := w.create(name, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.zip.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]fs.FileMode{"apps/blink/run.sh": 0755, "apps/blink/app.yaml": 0}
+	for _, f := range r.File {
+		if mode := entryMode(f); mode != expected[f.Name] {
+			t.Errorf("expected mode %o for %s, got %o", expected[f.Name], f.Name, mode)
+		}
+	}
+}
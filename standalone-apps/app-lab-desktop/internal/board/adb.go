@@ -0,0 +1,61 @@
+package board
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+	"github.com/arduino/arduino-app-cli/pkg/board/remote/adb"
+)
+
+// adbConn adds to the ADB connection the removal of a single port forwarding and
+// the reverse forwarding, that the remote interface does not provide.
+type adbConn struct {
+	remote.RemoteConn
+	serial string
+}
+
+func newAdbConn(conn remote.RemoteConn, serial string) remote.RemoteConn {
+	return &adbConn{RemoteConn: conn, serial: serial}
+}
+
+func (c *adbConn) ForwardKill(ctx context.Context, localPort int) error {
+	out, err := c.adb(ctx, "forward", "--remove", fmt.Sprintf("tcp:%d", localPort))
+	if err != nil {
+		return fmt.Errorf("adb forward --remove failed: %w: %s", err, out)
+	}
+	return nil
+}
+
+// ReverseForward makes the board port boardPort reach the host port localPort.
+func (c *adbConn) ReverseForward(ctx context.Context, boardPort, localPort int) error {
+	out, err := c.adb(ctx, "reverse", fmt.Sprintf("tcp:%d", boardPort), fmt.Sprintf("tcp:%d", localPort))
+	if err != nil {
+		return fmt.Errorf("adb reverse failed: %w: %s", err, out)
+	}
+	return nil
+}
+
+func (c *adbConn) ReverseKill(ctx context.Context, boardPort int) error {
+	out, err := c.adb(ctx, "reverse", "--remove", fmt.Sprintf("tcp:%d", boardPort))
+	if err != nil {
+		return fmt.Errorf("adb reverse --remove failed: %w: %s", err, out)
+	}
+	return nil
+}
+
+func (c *adbConn) adb(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, adb.FindAdbPath(), append([]string{"-s", c.serial}, args...)...)
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+func (c *adbConn) Close() error {
+	if closer, ok := c.RemoteConn.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
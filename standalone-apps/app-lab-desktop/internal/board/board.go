@@ -7,16 +7,22 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/arduino/arduino-app-cli/pkg/board"
 	"github.com/arduino/arduino-app-cli/pkg/board/remote"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/crypto/ssh"
 )
 
 const (
 	arduinoQFqbn          = "arduino:zephyr:unoq"
 	orchestratorTunnelTag = "orchestrator"
 	boardOrchestratorPort = 8800
+	pingTimeout           = 5 * time.Second
 )
 
 // This type is needed to avoid Wails name clash during JS bindings generation.
@@ -35,19 +41,41 @@ func (b BoardInfo) ToApiBoard() *board.Board {
 }
 
 type Board struct {
-	Id      string            `json:"id"`
-	Info    BoardInfo         `json:"info"`
-	Conn    remote.RemoteConn `json:"-"`
-	tunnels []tunnel.Tunnel
+	Id string `json:"id"`
+	// Identity identifies the physical board whatever the protocol it is reached with,
+	// it is the key of the board registry.
+	Identity string      `json:"identity"`
+	Info     BoardInfo   `json:"info"`
+	Origin   string      `json:"origin,omitempty"`
+	Known    *KnownBoard `json:"known,omitempty"`
+	sshPort  int
+	tunnels  *tunnel.Registry
+
+	// dial replaces the protocol connection when set, see NewWithDialer.
+	dial func(ctx context.Context) (remote.RemoteConn, error)
+
+	// connMu guards conn, replaced on reconnection while other goroutines use it, and
+	// address.
+	connMu sync.RWMutex
+	conn   remote.RemoteConn
+	// address replaces the discovered address of a network board found elsewhere
+	// since, see Relocate.
+	address string
+
+	// forwardErrorsMu guards forwardErrors, the last error of each forward by name.
+	forwardErrorsMu sync.Mutex
+	forwardErrors   map[string]string
 }
 
 func New(source *board.Board) (*Board, error) {
-	var id string
+	var id, identity string
 	if source != nil {
 		var err error
-		id, err = hashStruct(source)
-		if err != nil {
-			return nil, fmt.Errorf("failed to hash board struct: %w", err)
+		if identity, err = identityOf(source); err != nil {
+			return nil, err
+		}
+		if id, err = boardID(source.Protocol, identity); err != nil {
+			return nil, err
 		}
 	}
 
@@ -57,18 +85,61 @@ func New(source *board.Board) (*Board, error) {
 	}
 
 	return &Board{
-		Id:      id,
-		Info:    info,
-		Conn:    NoopConn(),
-		tunnels: nil,
+		Id:       id,
+		Identity: identity,
+		Info:     info,
+		conn:     NoopConn(),
+		tunnels:  tunnel.NewRegistry(),
 	}, nil
 }
 
+// Conn returns the current connection to the board, a no-op one while disconnected.
+func (b *Board) Conn() remote.RemoteConn {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+	return b.conn
+}
+
+// swapConn replaces the connection to the board and returns the previous one.
+func (b *Board) swapConn(conn remote.RemoteConn) remote.RemoteConn {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	prev := b.conn
+	b.conn = conn
+	return prev
+}
+
 func Noop() *Board {
 	noop, _ := New(nil)
 	return noop
 }
 
+// NewWithDialer returns a board connected with dial instead of its protocol, for the
+// transports the board package does not know, such as the fake ones of the tests.
+func NewWithDialer(identity string, dial func(ctx context.Context) (remote.RemoteConn, error)) *Board {
+	b := Noop()
+	b.Id = identity
+	b.Identity = identity
+	b.dial = dial
+	return b
+}
+
+// Clone returns a disconnected copy of the board, so that a connection can be
+// established without touching the instance shared by the board list.
+func (b *Board) Clone() *Board {
+	return &Board{
+		Id:       b.Id,
+		Identity: b.Identity,
+		Info:     b.Info,
+		Origin:   b.Origin,
+		Known:    b.Known,
+		conn:     NoopConn(),
+		sshPort:  b.sshPort,
+		tunnels:  tunnel.NewRegistry(),
+		dial:     b.dial,
+	}
+}
+
 func hashStruct(v any) (string, error) {
 	data, err := json.Marshal(v)
 	if err != nil {
@@ -80,37 +151,130 @@ func hashStruct(v any) (string, error) {
 }
 
 func (b *Board) StartTunnel(ctx context.Context, conn remote.RemoteConn, tag string, targetBoardPort int) (tunnel.Tunnel, error) {
-	for _, t := range b.tunnels {
-		if p, err := t.Port(); err != nil && p == targetBoardPort {
-			// @TODO: If needed by future requirements, close existing tunnel here and create a new one.
-			// Or allow multiple tunnels to the same port.
-			return t, nil
-		}
-	}
-
-	t, err := tunnel.New(ctx, conn, tag, targetBoardPort)
+	t, err := b.tunnels.Open(ctx, conn, tag, targetBoardPort)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start tunnel: %w", err)
 	}
-
-	b.tunnels = append(b.tunnels, t)
 	return t, nil
 }
 
 func (b *Board) CloseTunnels(ctx context.Context) {
-	if b.tunnels == nil || len(b.tunnels) == 0 {
-		runtime.LogInfof(ctx, "tunnels already closed")
+	if len(b.tunnels.List()) == 0 {
+		slog.Info("tunnels already closed")
+	}
+
+	if err := b.tunnels.CloseAll(ctx); err != nil {
+		slog.Error("failed to close tunnel", "err", err)
+	}
+}
+
+// ListTunnels returns the tunnels open on the board connection.
+func (b *Board) ListTunnels() []tunnel.Info {
+	return b.tunnels.List()
+}
+
+// SnapshotTunnels returns the tunnels open on the board connection, to open them
+// again with RestoreTunnels once reconnected.
+func (b *Board) SnapshotTunnels() tunnel.Snapshot {
+	return b.tunnels.Snapshot()
+}
+
+// RestoreTunnels opens again the tunnels of s closed by a reconnection, but the
+// forwards, that RestoreForwards opens. It returns the tunnels that could not be
+// opened again.
+func (b *Board) RestoreTunnels(ctx context.Context, s tunnel.Snapshot) []tunnel.Info {
+	s = s.DeleteFunc(func(t tunnel.Info) bool { return strings.HasPrefix(t.Tag, forwardTagPrefix) })
+	dropped, err := b.tunnels.Restore(ctx, b.Conn(), s)
+	if err != nil {
+		slog.Warn("failed to restore tunnels", "err", err)
+	}
+	return dropped
+}
+
+// TunnelStats returns the traffic statistics of the tunnels open on the board connection.
+func (b *Board) TunnelStats() []tunnel.Stats {
+	return b.tunnels.Stats()
+}
+
+// OpenTunnel forwards targetBoardPort to a local port, reusing the tunnel if it is
+// already open.
+func (b *Board) OpenTunnel(ctx context.Context, tag string, targetBoardPort int) (tunnel.Info, error) {
+	t, err := b.StartTunnel(ctx, b.Conn(), tag, targetBoardPort)
+	if err != nil {
+		return tunnel.Info{}, err
+	}
+	p, err := t.Port()
+	if err != nil {
+		return tunnel.Info{}, err
+	}
+	return tunnel.Info{Tag: t.Tag(), BoardPort: t.BoardPort(), LocalPort: p}, nil
+}
+
+// CloseTunnelByTag closes the tunnels with the given tag, the orchestrator one
+// excepted since the app cannot work without it.
+func (b *Board) CloseTunnelByTag(ctx context.Context, tag string) error {
+	if tag == orchestratorTunnelTag {
+		return fmt.Errorf("the orchestrator tunnel cannot be closed")
+	}
+	if err := b.tunnels.CloseByTag(ctx, tag); err != nil {
+		return fmt.Errorf("failed to close tunnel %s: %w", tag, err)
+	}
+	return nil
+}
+
+// CloseTunnelByPort closes the tunnels to the given board port.
+func (b *Board) CloseTunnelByPort(ctx context.Context, targetBoardPort int) error {
+	if targetBoardPort == boardOrchestratorPort {
+		return fmt.Errorf("the orchestrator tunnel cannot be closed")
 	}
+	if err := b.tunnels.CloseByPort(ctx, targetBoardPort); err != nil {
+		return fmt.Errorf("failed to close tunnel to port %d: %w", targetBoardPort, err)
+	}
+	return nil
+}
 
-	for _, t := range b.tunnels {
-		if err := t.Close(ctx); err != nil {
-			runtime.LogErrorf(ctx, "failed to close tunnel: %v", err)
+// Close tears down the tunnels and the connection to the board.
+func (b *Board) Close(ctx context.Context) {
+	b.CloseTunnels(ctx)
+	if c, ok := b.swapConn(NoopConn()).(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			slog.Error("failed to close board connection", "err", err)
 		}
 	}
-	b.tunnels = nil
 }
 
+// EstablishConnection connects to the board. Network boards use, in order, the given
+// password, the stored one and the app key; a given password that works is stored.
 func (b *Board) EstablishConnection(ctx context.Context, optPassword string) error {
+	network := b.Info.Protocol == board.NetworkProtocol
+
+	password := optPassword
+	if network && password == "" {
+		password = storedPassword(b.Identity)
+	}
+	if err := b.establishConnection(ctx, password); err != nil {
+		return &ConnectError{Identity: b.Identity, StoredPassword: optPassword == "" && password != "", Err: err}
+	}
+
+	if network && optPassword != "" {
+		rememberPassword(b.Identity, optPassword)
+	}
+	if network || b.Info.Protocol == board.SerialProtocol {
+		b.linkIdentities(ctx)
+	}
+	return nil
+}
+
+func (b *Board) establishConnection(ctx context.Context, optPassword string) error {
+	if b.dial != nil {
+		conn, err := b.dial(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect to board: %w", err)
+		}
+		b.swapConn(conn)
+		return nil
+	}
+
 	apiBoard := b.Info.ToApiBoard()
 	var conn remote.RemoteConn
 
@@ -121,6 +285,7 @@ func (b *Board) EstablishConnection(ctx context.Context, optPassword string) err
 		if err != nil {
 			return fmt.Errorf("failed to connect to board: %w", err)
 		}
+		conn = newAdbConn(conn, apiBoard.Serial)
 		if _, err := b.StartTunnel(ctx, conn, orchestratorTunnelTag, boardOrchestratorPort); err != nil {
 			return fmt.Errorf("failed to start tunnel: %w", err)
 		}
@@ -129,22 +294,27 @@ func (b *Board) EstablishConnection(ctx context.Context, optPassword string) err
 		go func() {
 			s, err := board.NetworkModeStatus(ctx, conn)
 			if err != nil {
-				runtime.LogErrorf(ctx, "failed to get network mode status: %v", err)
+				slog.Error("failed to get network mode status", "err", err)
 				return
 			}
 			if !s {
 				if err := board.EnableNetworkMode(ctx, conn); err != nil {
-					runtime.LogErrorf(ctx, "failed to enable network mode: %v", err)
+					slog.Error("failed to enable network mode", "err", err)
 				}
 			}
 		}()
 
 	case board.NetworkProtocol:
 		var err error
-		if optPassword == "" {
+		if optPassword == "" && !HasAppKey() {
 			return fmt.Errorf("password is required to connect to network protocol board")
 		}
-		conn, err = apiBoard.GetConnection(optPassword)
+		// The app own SSH client checks the pinned host key on the connection that
+		// authenticates, so that the password is never sent to another host.
+		var auth []ssh.AuthMethod
+		if auth, err = sshAuth(optPassword); err == nil {
+			conn, err = dialSSH(ctx, b.Identity, b.dialAddress(), b.sshPort, auth)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to connect to board: %w", err)
 		}
@@ -163,32 +333,80 @@ func (b *Board) EstablishConnection(ctx context.Context, optPassword string) err
 		return fmt.Errorf("unsupported board protocol: %s", apiBoard.Protocol)
 	}
 
-	b.Conn = conn
+	b.swapConn(conn)
+	return nil
+}
+
+// Relocate makes the next connections reach the board where found, the same board
+// discovered again, e.g. with a new address after a reboot. It fails if found is
+// another board.
+func (b *Board) Relocate(found *Board) error {
+	if found.Identity != b.Identity || found.Info.Protocol != b.Info.Protocol {
+		return fmt.Errorf("board %s came back as %s", b.Identity, found.Identity)
+	}
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	if found.Info.Address != b.Info.Address {
+		b.address = found.Info.Address
+	} else {
+		b.address = ""
+	}
+	return nil
+}
+
+func (b *Board) dialAddress() string {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+	if b.address != "" {
+		return b.address
+	}
+	return b.Info.Address
+}
+
+// Reconnect closes the connection to the board, that may be already dead, and
+// establishes it again together with the orchestrator tunnel.
+func (b *Board) Reconnect(ctx context.Context, optPassword string) error {
+	b.Close(ctx)
+	return b.EstablishConnection(ctx, optPassword)
+}
+
+// Ping checks that the board still answers on its connection.
+func (b *Board) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	if err := b.Conn().GetCmd("true").Run(ctx); err != nil {
+		return fmt.Errorf("board is not reachable: %w", err)
+	}
 	return nil
 }
 
 func (b *Board) GetName(ctx context.Context) (string, error) {
-	return board.GetCustomName(ctx, b.Conn)
+	return board.GetCustomName(ctx, b.Conn())
 }
 
 func (b *Board) SetName(ctx context.Context, name string) error {
-	return board.SetCustomName(ctx, b.Conn, name)
+	return board.SetCustomName(ctx, b.Conn(), name)
 }
 
 func (b *Board) IsUserPasswordSet(ctx context.Context) (bool, error) {
-	return board.IsUserPasswordSet(b.Conn)
+	return board.IsUserPasswordSet(b.Conn())
 }
 
 func (b *Board) SetUserPassword(ctx context.Context, password string) error {
-	return board.SetUserPassword(ctx, b.Conn, password)
+	if err := board.SetUserPassword(ctx, b.Conn(), password); err != nil {
+		return err
+	}
+	updateStoredPassword(b.Identity, password)
+	return nil
 }
 
 func (b *Board) GetKeyboardLayout(ctx context.Context) (string, error) {
-	return board.GetKeyboardLayout(ctx, b.Conn)
+	return board.GetKeyboardLayout(ctx, b.Conn())
 }
 
 func (b *Board) ListKeyboardLayouts() ([]KeyboardLayout, error) {
-	boardLayouts, err := board.ListKeyboardLayouts(b.Conn)
+	boardLayouts, err := board.ListKeyboardLayouts(b.Conn())
 	if err != nil {
 		return nil, err
 	}
@@ -203,34 +421,27 @@ func (b *Board) ListKeyboardLayouts() ([]KeyboardLayout, error) {
 }
 
 func (b *Board) SetKeyboardLayout(ctx context.Context, layoutCode string) error {
-	return board.SetKeyboardLayout(ctx, b.Conn, layoutCode)
+	return board.SetKeyboardLayout(ctx, b.Conn(), layoutCode)
 }
 
 func (b *Board) GetOrchestratorURL() (string, error) {
-	if len(b.tunnels) == 0 {
+	if len(b.tunnels.List()) == 0 {
 		return "", fmt.Errorf("no active tunnels")
 	}
 
-	var port int
-	for _, t := range b.tunnels {
-		if t.Tag() == "orchestrator" {
-			p, err := t.Port()
-			if err != nil {
-				return "", fmt.Errorf("failed to get orchestrator tunnel port: %w", err)
-			}
-			port = p
-			break
-		}
-	}
-
-	if port == 0 {
+	t, ok := b.tunnels.Find(orchestratorTunnelTag)
+	if !ok {
 		return "", fmt.Errorf("no orchestrator tunnel found")
 	}
+	port, err := t.Port()
+	if err != nil {
+		return "", fmt.Errorf("failed to get orchestrator tunnel port: %w", err)
+	}
 	return fmt.Sprintf("http://localhost:%d", port), nil
 }
 
 func (b *Board) IsR0Build() bool {
-	_, err := b.Conn.Stats("/etc/buildinfo")
+	_, err := b.Conn().Stats("/etc/buildinfo")
 	// if the file does not exist, it's an R0 build
 	return err != nil
 }
@@ -0,0 +1,169 @@
+package board
+
+import (
+	"app-lab-desktop/internal/sshconn"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// killTimeout bounds the kill of a cancelled command, the connection may be gone.
+const killTimeout = 5 * time.Second
+
+type OutputStream string
+
+const (
+	Stdout OutputStream = "stdout"
+	Stderr OutputStream = "stderr"
+)
+
+// CommandResult is the outcome of a command run to completion.
+type CommandResult struct {
+	ExitCode int    `json:"exitCode"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// RunCommand runs name with args on the board and returns its output and exit code.
+func (b *Board) RunCommand(ctx context.Context, name string, args []string) (CommandResult, error) {
+	var mu sync.Mutex
+	var stdout, stderr bytes.Buffer
+	code, err := b.StreamCommand(ctx, name, args, func(stream OutputStream, data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		if stream == Stdout {
+			stdout.Write(data)
+		} else {
+			stderr.Write(data)
+		}
+	})
+	if err != nil {
+		return CommandResult{}, err
+	}
+	return CommandResult{ExitCode: code, Stdout: stdout.String(), Stderr: stderr.String()}, nil
+}
+
+// StreamCommand runs name with args on the board, passing the output chunks to
+// onOutput as they come, and returns the exit code. When ctx is done, the command is
+// killed on the board.
+//
+// The command line goes through the standard input of a shell, since the connections
+// do not quote the arguments the same way. The shell first prints its pid on stderr,
+// then replaces itself with the command, so that it can be killed.
+func (b *Board) StreamCommand(ctx context.Context, name string, args []string, onOutput func(OutputStream, []byte)) (int, error) {
+	if strings.TrimSpace(name) == "" {
+		return -1, errors.New("empty command")
+	}
+
+	stdin, stdout, stderr, closer, err := b.Conn().GetCmd("sh").Interactive()
+	if err != nil {
+		return -1, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	words := []string{sshconn.Quote(name)}
+	for _, a := range args {
+		words = append(words, sshconn.Quote(a))
+	}
+	script := "echo $$ >&2\nexec " + strings.Join(words, " ") + " </dev/null\n"
+	_, err = io.WriteString(stdin, script)
+	_ = stdin.Close()
+	if err != nil {
+		return -1, errors.Join(fmt.Errorf("failed to start command: %w", err), closer())
+	}
+
+	stderrReader := bufio.NewReader(stderr)
+	pidLine, err := stderrReader.ReadString('\n')
+	if err != nil {
+		return -1, errors.Join(fmt.Errorf("failed to start command: %w", err), closer())
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(pidLine))
+	if err != nil {
+		return -1, errors.Join(fmt.Errorf("failed to start command: unexpected output %q", pidLine), closer())
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		pipeOutput(stdout, Stdout, onOutput)
+	}()
+	go func() {
+		defer wg.Done()
+		pipeOutput(stderrReader, Stderr, onOutput)
+	}()
+
+	done := make(chan struct{})
+	cancelled := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			close(cancelled)
+			b.killCommand(pid)
+		case <-done:
+		}
+	}()
+
+	wg.Wait()
+	err = closer()
+	close(done)
+
+	select {
+	case <-cancelled:
+		return -1, ctx.Err()
+	default:
+	}
+	if err == nil {
+		return 0, nil
+	}
+	if code, ok := exitCode(err); ok {
+		return code, nil
+	}
+	return -1, fmt.Errorf("command failed: %w", err)
+}
+
+// killCommand terminates the command with the given pid, and its children.
+func (b *Board) killCommand(pid int) {
+	ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+	defer cancel()
+
+	p := strconv.Itoa(pid)
+	_ = b.Conn().GetCmd("pkill", "-TERM", "-P", p).Run(ctx)
+	if err := b.Conn().GetCmd("kill", "-TERM", p).Run(ctx); err != nil {
+		slog.Warn("failed to kill command", "pid", pid, "err", err)
+	}
+}
+
+func pipeOutput(r io.Reader, stream OutputStream, onOutput func(OutputStream, []byte)) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			onOutput(stream, bytes.Clone(buf[:n]))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// exitCode returns the exit code carried by the error of a finished command, for
+// both the local processes (adb) and the SSH sessions.
+func exitCode(err error) (int, bool) {
+	var exitCoder interface{ ExitCode() int }
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode(), true
+	}
+	var exitStatuser interface{ ExitStatus() int }
+	if errors.As(err, &exitStatuser) {
+		return exitStatuser.ExitStatus(), true
+	}
+	return 0, false
+}
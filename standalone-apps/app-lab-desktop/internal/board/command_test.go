@@ -0,0 +1,201 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+)
+
+// fakeConn runs the scripts written to sh as a shell printing the pid, then the
+// output of the command. The other commands are the kills of a cancelled one.
+type fakeConn struct {
+	remote.RemoteConn
+	pidLine string
+	stdout  string
+	stderr  string
+	// exit is returned once the command is done.
+	exit error
+	// hang keeps the command running until it is killed.
+	hang bool
+
+	mu      sync.Mutex
+	scripts []string
+	kills   []string
+	killed  chan struct{}
+}
+
+func (c *fakeConn) GetCmd(cmd string, args ...string) remote.Cmder {
+	return &fakeCmder{conn: c, line: strings.Join(append([]string{cmd}, args...), " ")}
+}
+
+type fakeCmder struct {
+	remote.Cmder
+	conn *fakeConn
+	line string
+}
+
+func (c *fakeCmder) Run(context.Context) error {
+	c.conn.mu.Lock()
+	defer c.conn.mu.Unlock()
+	c.conn.kills = append(c.conn.kills, c.line)
+	if strings.HasPrefix(c.line, "kill ") {
+		close(c.conn.killed)
+	}
+	return nil
+}
+
+func (c *fakeCmder) Interactive() (io.WriteCloser, io.Reader, io.Reader, remote.Closer, error) {
+	stdin := &scriptWriter{conn: c.conn}
+	stdout := io.Reader(strings.NewReader(c.conn.stdout))
+	if c.conn.hang {
+		r, w := io.Pipe()
+		go func() {
+			<-c.conn.killed
+			w.CloseWithError(io.EOF)
+		}()
+		stdout = r
+	}
+	closer := func() error {
+		if c.conn.hang {
+			<-c.conn.killed
+		}
+		return c.conn.exit
+	}
+	return stdin, stdout, strings.NewReader(c.conn.pidLine + c.conn.stderr), closer, nil
+}
+
+type scriptWriter struct {
+	strings.Builder
+	conn *fakeConn
+}
+
+func (w *scriptWriter) Close() error {
+	w.conn.mu.Lock()
+	defer w.conn.mu.Unlock()
+	w.conn.scripts = append(w.conn.scripts, w.String())
+	return nil
+}
+
+func newFakeBoard(conn *fakeConn) *Board {
+	conn.killed = make(chan struct{})
+	b := Noop()
+	b.swapConn(conn)
+	return b
+}
+
+type exitCodeError int
+
+func (e exitCodeError) Error() string { return "exit status " + strconv.Itoa(int(e)) }
+
+func (e exitCodeError) ExitCode() int { return int(e) }
+
+// exitStatusError is like the ssh.ExitError of the SSH sessions.
+type exitStatusError int
+
+func (e exitStatusError) Error() string { return "Process exited with status " + strconv.Itoa(int(e)) }
+
+func (e exitStatusError) ExitStatus() int { return int(e) }
+
+func TestRunCommand(t *testing.T) {
+	conn := &fakeConn{pidLine: "42\n", stdout: "hello\n", stderr: "warning\n", exit: exitCodeError(2)}
+	b := newFakeBoard(conn)
+
+	res, err := b.RunCommand(context.Background(), "echo", []string{"hello world", "it's"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := CommandResult{ExitCode: 2, Stdout: "hello\n", Stderr: "warning\n"}
+	if res != expected {
+		t.Errorf("expected %+v, got %+v", expected, res)
+	}
+	// The pid line is the handshake, it is not part of the output.
+	script := "echo $$ >&2\nexec echo 'hello world' 'it'\\''s' </dev/null\n"
+	if len(conn.scripts) != 1 || conn.scripts[0] != script {
+		t.Errorf("expected script %q, got %q", script, conn.scripts)
+	}
+}
+
+func TestRunCommandHandshake(t *testing.T) {
+	tests := []struct {
+		name    string
+		pidLine string
+	}{
+		{name: "no pid", pidLine: ""},
+		{name: "unexpected output", pidLine: "sh: not found\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newFakeBoard(&fakeConn{pidLine: tt.pidLine})
+			if _, err := b.RunCommand(context.Background(), "true", nil); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+	if _, err := newFakeBoard(&fakeConn{}).RunCommand(context.Background(), " ", nil); err == nil {
+		t.Errorf("expected an error for an empty command")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+		ok       bool
+	}{
+		{name: "local process", err: exitCodeError(3), expected: 3, ok: true},
+		{name: "ssh session", err: exitStatusError(127), expected: 127, ok: true},
+		{name: "wrapped", err: errors.Join(errors.New("closing"), exitStatusError(1)), expected: 1, ok: true},
+		{name: "connection lost", err: errors.New("EOF"), expected: 0, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ok := exitCode(tt.err)
+			if code != tt.expected || ok != tt.ok {
+				t.Errorf("expected %d, %v, got %d, %v", tt.expected, tt.ok, code, ok)
+			}
+		})
+	}
+
+	// A failure without exit code is an error, not an exit code.
+	b := newFakeBoard(&fakeConn{pidLine: "42\n", exit: errors.New("EOF")})
+	if code, err := b.StreamCommand(context.Background(), "true", nil, func(OutputStream, []byte) {}); err == nil || code != -1 {
+		t.Errorf("expected -1 and an error, got %d, %v", code, err)
+	}
+}
+
+func TestStreamCommandCancel(t *testing.T) {
+	conn := &fakeConn{pidLine: "42\n", hang: true, exit: exitStatusError(143)}
+	b := newFakeBoard(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.StreamCommand(ctx, "sleep", []string{"60"}, func(OutputStream, []byte) {})
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the command to be killed")
+	}
+	// The children of the shell are killed first, then the shell itself.
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	expected := []string{"pkill -TERM -P 42", "kill -TERM 42"}
+	if strings.Join(conn.kills, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected kills %q, got %q", expected, conn.kills)
+	}
+}
@@ -0,0 +1,145 @@
+package board
+
+import (
+	"app-lab-desktop/internal/config"
+	"app-lab-desktop/internal/vault"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	appssh "github.com/arduino/arduino-app-cli/pkg/board/remote/ssh"
+)
+
+const (
+	vaultFile    = "credentials.vault"
+	vaultKeyFile = "credentials.key"
+	// vaultPassphraseEnv unlocks a vault protected by a passphrase, for headless use.
+	vaultPassphraseEnv = "ARDUINO_APP_LAB_VAULT_PASSPHRASE"
+)
+
+var (
+	credentialsMu sync.Mutex
+	credentials   *vault.Vault
+)
+
+// ConnectError is returned by EstablishConnection, it tells which board failed so
+// that its stored credentials can be invalidated.
+type ConnectError struct {
+	Identity string
+	// StoredPassword is set if the password tried was the stored one.
+	StoredPassword bool
+	Err            error
+}
+
+func (e *ConnectError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Credentials returns the vault of the board passwords, opening it on first use.
+func Credentials() (*vault.Vault, error) {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+
+	if credentials != nil {
+		return credentials, nil
+	}
+
+	vaultPath, err := config.Path(vaultFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPath, err := config.Path(vaultKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	v, err := vault.Open(vaultPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credential vault: %w", err)
+	}
+	if passphrase := os.Getenv(vaultPassphraseEnv); passphrase != "" {
+		if err := v.Unlock(passphrase); err != nil {
+			return nil, err
+		}
+	}
+	credentials = v
+	return v, nil
+}
+
+// storedPassword returns the password stored for the board, or an empty string.
+func storedPassword(identity string) string {
+	v, err := Credentials()
+	if err != nil {
+		slog.Error("failed to open credential vault", "err", err)
+		return ""
+	}
+	password, err := v.Get(identity)
+	if err != nil {
+		if !errors.Is(err, vault.ErrNotFound) {
+			slog.Warn("failed to read stored password", "err", err)
+		}
+		return ""
+	}
+	return password
+}
+
+// StoredPasswords returns every stored password, none while the vault is locked.
+func StoredPasswords() []string {
+	v, err := Credentials()
+	if err != nil {
+		slog.Error("failed to open credential vault", "err", err)
+		return nil
+	}
+	var passwords []string
+	for _, e := range v.List() {
+		if password, err := v.Get(e.Identity); err == nil {
+			passwords = append(passwords, password)
+		}
+	}
+	return passwords
+}
+
+func rememberPassword(identity string, password string) {
+	v, err := Credentials()
+	if err != nil {
+		slog.Error("failed to open credential vault", "err", err)
+		return
+	}
+	if err := v.Put(identity, password); err != nil && !errors.Is(err, vault.ErrLocked) {
+		slog.Error("failed to store password", "err", err)
+	}
+}
+
+// updateStoredPassword replaces the stored password of the board, if any.
+func updateStoredPassword(identity string, password string) {
+	if storedPassword(identity) != "" {
+		rememberPassword(identity, password)
+	}
+}
+
+// ForgetPassword removes the stored password of the board.
+func ForgetPassword(identity string) error {
+	v, err := Credentials()
+	if err != nil {
+		return err
+	}
+	return v.Forget(identity)
+}
+
+// InvalidateCredentials removes the stored password of the board that failed to
+// connect with err, if the board rejected it. A wrong password typed by the user
+// leaves the stored one untouched.
+func InvalidateCredentials(err error) {
+	var connectErr *ConnectError
+	if !errors.Is(err, appssh.ErrAuthFailed) || !errors.As(err, &connectErr) || !connectErr.StoredPassword {
+		return
+	}
+	if err := ForgetPassword(connectErr.Identity); err != nil {
+		slog.Error("failed to forget stored password", "err", err)
+	}
+}
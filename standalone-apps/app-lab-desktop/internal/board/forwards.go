@@ -0,0 +1,274 @@
+package board
+
+import (
+	"app-lab-desktop/internal/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+const (
+	forwardsFile        = "forwards.json"
+	forwardTagPrefix    = "forward:"
+	ForwardActive       = "active"
+	ForwardInactive     = "inactive"
+	ForwardFailed       = "failed"
+	ForwardDisconnected = "disconnected"
+)
+
+// Forward is a user-defined port forwarding to a service of the board, e.g. a
+// Jupyter server or an MQTT broker started by a brick.
+type Forward struct {
+	Name      string `json:"name"`
+	BoardPort int    `json:"boardPort"`
+	// LocalPort is the port on the host, 0 picks the board port or an available one.
+	LocalPort int `json:"localPort,omitempty"`
+}
+
+type ForwardStatus struct {
+	Forward
+	Status string `json:"status"`
+	// ActivePort is the local port in use while the forward is active.
+	ActivePort int    `json:"activePort,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// forwardsMu guards the forwards file.
+var forwardsMu sync.Mutex
+
+// ListForwards returns the forwards of the board with their status.
+func (b *Board) ListForwards() ([]ForwardStatus, error) {
+	forwards, err := b.loadForwards()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ForwardStatus, 0, len(forwards))
+	for _, f := range forwards {
+		statuses = append(statuses, b.forwardStatus(f))
+	}
+	return statuses, nil
+}
+
+// AddForward persists the forward and opens it if the board is connected.
+func (b *Board) AddForward(ctx context.Context, f Forward) (ForwardStatus, error) {
+	if f.Name == "" {
+		return ForwardStatus{}, fmt.Errorf("forward name is required")
+	}
+	if f.BoardPort <= 0 || f.BoardPort > 65535 {
+		return ForwardStatus{}, fmt.Errorf("invalid board port %d", f.BoardPort)
+	}
+	if f.LocalPort < 0 || f.LocalPort > 65535 {
+		return ForwardStatus{}, fmt.Errorf("invalid local port %d", f.LocalPort)
+	}
+
+	err := b.updateForwards(func(forwards []Forward) ([]Forward, error) {
+		if slices.ContainsFunc(forwards, func(e Forward) bool { return e.Name == f.Name }) {
+			return nil, fmt.Errorf("forward %s already exists", f.Name)
+		}
+		return append(forwards, f), nil
+	})
+	if err != nil {
+		return ForwardStatus{}, err
+	}
+
+	if b.isConnected() {
+		b.openForward(ctx, f)
+	}
+	return b.forwardStatus(f), nil
+}
+
+// RemoveForward closes the forward and removes it from the board forwards.
+func (b *Board) RemoveForward(ctx context.Context, name string) error {
+	err := b.updateForwards(func(forwards []Forward) ([]Forward, error) {
+		n := len(forwards)
+		forwards = slices.DeleteFunc(forwards, func(e Forward) bool { return e.Name == name })
+		if len(forwards) == n {
+			return nil, fmt.Errorf("forward %s not found", name)
+		}
+		return forwards, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	b.setForwardError(name, nil)
+	if _, ok := b.tunnels.Find(forwardTagPrefix + name); ok {
+		if err := b.tunnels.CloseByTag(ctx, forwardTagPrefix+name); err != nil {
+			return fmt.Errorf("failed to close forward %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// OpenForward opens the forward again, e.g. after its local port has been freed.
+func (b *Board) OpenForward(ctx context.Context, name string) (ForwardStatus, error) {
+	forwards, err := b.loadForwards()
+	if err != nil {
+		return ForwardStatus{}, err
+	}
+	i := slices.IndexFunc(forwards, func(e Forward) bool { return e.Name == name })
+	if i == -1 {
+		return ForwardStatus{}, fmt.Errorf("forward %s not found", name)
+	}
+	if !b.isConnected() {
+		return ForwardStatus{}, fmt.Errorf("board is not connected")
+	}
+
+	b.openForward(ctx, forwards[i])
+	status := b.forwardStatus(forwards[i])
+	if status.Status == ForwardFailed {
+		return status, fmt.Errorf("failed to open forward %s: %s", name, status.Error)
+	}
+	return status, nil
+}
+
+// RestoreForwards opens every forward of the board, it is called once connected.
+// Failures are kept in the forward status instead of failing the connection.
+func (b *Board) RestoreForwards(ctx context.Context) {
+	forwards, err := b.loadForwards()
+	if err != nil {
+		slog.Error("failed to load forwards", "err", err)
+		return
+	}
+	for _, f := range forwards {
+		b.openForward(ctx, f)
+	}
+}
+
+func (b *Board) openForward(ctx context.Context, f Forward) {
+	// Each forward has a tunnel of its own, so that removing it keeps e.g. an app UI
+	// on the same board port open.
+	var err error
+	if f.LocalPort == 0 {
+		_, err = b.tunnels.OpenWithLocalPort(ctx, b.Conn(), forwardTagPrefix+f.Name, f.BoardPort, f.BoardPort, true)
+	} else {
+		_, err = b.tunnels.OpenWithLocalPort(ctx, b.Conn(), forwardTagPrefix+f.Name, f.LocalPort, f.BoardPort, false)
+	}
+	if err != nil {
+		slog.Warn("failed to open forward", "name", f.Name, "err", err)
+	}
+	b.setForwardError(f.Name, err)
+}
+
+func (b *Board) forwardStatus(f Forward) ForwardStatus {
+	status := ForwardStatus{Forward: f, Status: ForwardInactive}
+
+	b.forwardErrorsMu.Lock()
+	status.Error = b.forwardErrors[f.Name]
+	b.forwardErrorsMu.Unlock()
+
+	for _, t := range b.tunnels.List() {
+		if t.Tag == forwardTagPrefix+f.Name {
+			status.Status = ForwardActive
+			status.ActivePort = t.LocalPort
+			status.Error = ""
+			return status
+		}
+	}
+
+	switch {
+	case status.Error != "":
+		status.Status = ForwardFailed
+	case !b.isConnected():
+		status.Status = ForwardDisconnected
+	}
+	return status
+}
+
+func (b *Board) setForwardError(name string, err error) {
+	b.forwardErrorsMu.Lock()
+	defer b.forwardErrorsMu.Unlock()
+
+	if err == nil {
+		delete(b.forwardErrors, name)
+		return
+	}
+	if b.forwardErrors == nil {
+		b.forwardErrors = map[string]string{}
+	}
+	b.forwardErrors[name] = err.Error()
+}
+
+func (b *Board) isConnected() bool {
+	_, noop := b.Conn().(*noopConnection)
+	return !noop
+}
+
+func (b *Board) loadForwards() ([]Forward, error) {
+	if b.Identity == "" {
+		return nil, fmt.Errorf("no board selected")
+	}
+
+	identity := settingsIdentity(b.Identity)
+	forwardsMu.Lock()
+	defer forwardsMu.Unlock()
+
+	all, err := loadAllForwards()
+	if err != nil {
+		return nil, err
+	}
+	return all[identity], nil
+}
+
+func (b *Board) updateForwards(update func([]Forward) ([]Forward, error)) error {
+	if b.Identity == "" {
+		return fmt.Errorf("no board selected")
+	}
+
+	identity := settingsIdentity(b.Identity)
+	forwardsMu.Lock()
+	defer forwardsMu.Unlock()
+
+	all, err := loadAllForwards()
+	if err != nil {
+		return err
+	}
+	forwards, err := update(all[identity])
+	if err != nil {
+		return err
+	}
+	if len(forwards) == 0 {
+		delete(all, identity)
+	} else {
+		all[identity] = forwards
+	}
+	if err := config.Save(forwardsFile, all); err != nil {
+		return fmt.Errorf("failed to save forwards: %w", err)
+	}
+	return nil
+}
+
+// renameForwards moves the forwards of legacy to identity, unless it has its own.
+func renameForwards(legacy string, identity string) error {
+	forwardsMu.Lock()
+	defer forwardsMu.Unlock()
+
+	all, err := loadAllForwards()
+	if err != nil {
+		return err
+	}
+	forwards, ok := all[legacy]
+	if !ok {
+		return nil
+	}
+	if _, ok := all[identity]; !ok {
+		all[identity] = forwards
+	}
+	delete(all, legacy)
+	if err := config.Save(forwardsFile, all); err != nil {
+		return fmt.Errorf("failed to save forwards: %w", err)
+	}
+	return nil
+}
+
+// loadAllForwards returns the forwards of every board, by identity.
+func loadAllForwards() (map[string][]Forward, error) {
+	all := map[string][]Forward{}
+	if err := config.Load(forwardsFile, &all); err != nil {
+		return nil, fmt.Errorf("failed to load forwards: %w", err)
+	}
+	return all, nil
+}
@@ -0,0 +1,116 @@
+package board
+
+import (
+	"app-lab-desktop/internal/config"
+	"app-lab-desktop/internal/sshconn"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const hostKeysFile = "host-keys.json"
+
+// PinnedHostKey is the SSH host key recorded the first time a board has been connected.
+type PinnedHostKey struct {
+	Identity    string    `json:"identity"`
+	Address     string    `json:"address"`
+	KeyType     string    `json:"keyType"`
+	Fingerprint string    `json:"fingerprint"`
+	PinnedAt    time.Time `json:"pinnedAt"`
+}
+
+var hostKeysMu sync.Mutex
+
+// hostKeyCallback trusts the host key of a board on first use and rejects any other
+// key later on.
+func hostKeyCallback(identity string) ssh.HostKeyCallback {
+	return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		return verifyHostKey(identity, hostname, key)
+	}
+}
+
+// checkHostKey verifies the host key of the board at addr without authenticating, it
+// must not be followed by another connection sending credentials, that could reach
+// another host.
+func checkHostKey(ctx context.Context, identity string, addr string) error {
+	key, err := sshconn.HostKey(ctx, addr)
+	if err != nil {
+		return err
+	}
+	return verifyHostKey(identity, addr, key)
+}
+
+// verifyHostKey checks the host key of the board against the one pinned for its
+// identity, pinning it on first use.
+func verifyHostKey(identity string, addr string, key ssh.PublicKey) error {
+	hostKeysMu.Lock()
+	defer hostKeysMu.Unlock()
+
+	pinned, err := loadHostKeys()
+	if err != nil {
+		return err
+	}
+
+	fingerprint := ssh.FingerprintSHA256(key)
+	i := slices.IndexFunc(pinned, func(p PinnedHostKey) bool { return p.Identity == identity })
+	if i != -1 {
+		if pinned[i].Fingerprint != fingerprint {
+			return &sshconn.HostKeyMismatchError{
+				Identity: identity,
+				Address:  addr,
+				Expected: pinned[i].Fingerprint,
+				Got:      fingerprint,
+			}
+		}
+		return nil
+	}
+
+	pinned = append(pinned, PinnedHostKey{
+		Identity:    identity,
+		Address:     addr,
+		KeyType:     key.Type(),
+		Fingerprint: fingerprint,
+		PinnedAt:    time.Now(),
+	})
+	if err := config.Save(hostKeysFile, pinned); err != nil {
+		return fmt.Errorf("failed to pin host key: %w", err)
+	}
+	return nil
+}
+
+func PinnedHostKeys() ([]PinnedHostKey, error) {
+	hostKeysMu.Lock()
+	defer hostKeysMu.Unlock()
+	return loadHostKeys()
+}
+
+// ResetHostKey forgets the pinned host key of a board, the next key it presents is
+// trusted.
+func ResetHostKey(identity string) error {
+	hostKeysMu.Lock()
+	defer hostKeysMu.Unlock()
+
+	pinned, err := loadHostKeys()
+	if err != nil {
+		return err
+	}
+	n := len(pinned)
+	pinned = slices.DeleteFunc(pinned, func(p PinnedHostKey) bool { return p.Identity == identity })
+	if len(pinned) == n {
+		return fmt.Errorf("no host key pinned for board %s", identity)
+	}
+	return config.Save(hostKeysFile, pinned)
+}
+
+func loadHostKeys() ([]PinnedHostKey, error) {
+	var pinned []PinnedHostKey
+	if err := config.Load(hostKeysFile, &pinned); err != nil {
+		return nil, fmt.Errorf("failed to load pinned host keys: %w", err)
+	}
+	return pinned, nil
+}
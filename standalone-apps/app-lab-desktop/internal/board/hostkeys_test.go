@@ -0,0 +1,59 @@
+package board
+
+import (
+	"app-lab-desktop/internal/sshconn"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerifyHostKey(t *testing.T) {
+	t.Setenv("ARDUINO_APP_LAB_CONFIG_DIR", t.TempDir())
+	key, other := newHostKey(t), newHostKey(t)
+	identity := addressIdentityPrefix + "10.0.0.2"
+
+	// The first key is pinned, then accepted again.
+	for range 2 {
+		if err := verifyHostKey(identity, "10.0.0.2:22", key); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Another board answering at the known address is rejected, not registered as a
+	// new one.
+	var mismatch *sshconn.HostKeyMismatchError
+	if err := verifyHostKey(identity, "10.0.0.2:22", other); !errors.As(err, &mismatch) {
+		t.Fatalf("expected a host key mismatch, got %v", err)
+	}
+	if mismatch.Expected != ssh.FingerprintSHA256(key) || mismatch.Got != ssh.FingerprintSHA256(other) {
+		t.Errorf("expected mismatch %s != %s, got %+v", ssh.FingerprintSHA256(key), ssh.FingerprintSHA256(other), mismatch)
+	}
+	pinned, err := PinnedHostKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pinned) != 1 || pinned[0].Identity != identity || pinned[0].Fingerprint != ssh.FingerprintSHA256(key) {
+		t.Errorf("expected only the first key to be pinned, got %+v", pinned)
+	}
+
+	// Another address is another board, with its own pin.
+	if err := verifyHostKey(addressIdentityPrefix+"10.0.0.3", "10.0.0.3:22", other); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package board
+
+import (
+	"app-lab-desktop/internal/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arduino/arduino-app-cli/pkg/board"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	serialIdentityPrefix  = "serial:"
+	addressIdentityPrefix = "address:"
+
+	// hostKeysReadTimeout bounds the reading of the host keys of a board over USB.
+	hostKeysReadTimeout = 5 * time.Second
+)
+
+// identityOf returns the identity of the board known from its discovery: its serial
+// number when known, its address otherwise. The identity never comes from the board
+// host key, which is only checked against the one pinned for the identity.
+func identityOf(source *board.Board) (string, error) {
+	switch {
+	case source.Serial != "":
+		return serialIdentityPrefix + source.Serial, nil
+	case source.Address != "":
+		return addressIdentityPrefix + source.Address, nil
+	default:
+		h, err := hashStruct(source)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash board struct: %w", err)
+		}
+		return "board:" + h, nil
+	}
+}
+
+// boardID returns the id of a board. It only depends on the identity and the protocol,
+// so that it survives a rename or a new IP address, while the same board reachable
+// over both USB and network is listed twice.
+func boardID(protocol string, identity string) (string, error) {
+	id, err := hashStruct(struct {
+		Protocol string
+		Identity string
+	}{protocol, identity})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash board struct: %w", err)
+	}
+	return id, nil
+}
+
+// linkIdentities links the network identity of the board to its USB one once
+// connected, so that its settings follow it. Over USB, the host keys of the board are
+// recorded; over the network, the key pinned for its address is the one it presented.
+func (b *Board) linkIdentities(ctx context.Context) {
+	if b.Info.Protocol == board.SerialProtocol {
+		ctx, cancel := context.WithTimeout(ctx, hostKeysReadTimeout)
+		defer cancel()
+		fingerprints, err := b.readHostKeys(ctx)
+		if err != nil {
+			slog.Warn("failed to read board host keys", "identity", b.Identity, "err", err)
+			return
+		}
+		if err := updateKnownBoard(b.Identity, func(k *KnownBoard) error {
+			k.HostKeys = fingerprints
+			return nil
+		}); err != nil {
+			slog.Error("failed to record board host keys", "err", err)
+			return
+		}
+	}
+
+	pinned, err := PinnedHostKeys()
+	if err != nil {
+		slog.Error("failed to link board identities", "err", err)
+		return
+	}
+	linked, err := linkKnownBoards(pinned)
+	if err != nil {
+		slog.Error("failed to link board identities", "err", err)
+		return
+	}
+	for network, usb := range linked {
+		slog.Info("linking board identities", "network", network, "usb", usb)
+		if err := renameForwards(network, usb); err != nil {
+			slog.Error("failed to move board forwards", "err", err)
+		}
+	}
+}
+
+// readHostKeys returns the fingerprints of the SSH host keys of the board.
+func (b *Board) readHostKeys(ctx context.Context) ([]string, error) {
+	out, err := b.output(ctx, "sh", "-c", "cat /etc/ssh/ssh_host_*_key.pub")
+	if err != nil {
+		return nil, err
+	}
+	var fingerprints []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse host key: %w", err)
+		}
+		fingerprints = append(fingerprints, ssh.FingerprintSHA256(key))
+	}
+	return fingerprints, nil
+}
+
+// linkKnownBoards links every network entry of the registry whose pinned host key is
+// one of the host keys of a USB entry to it. The nickname and preferred protocol of a
+// newly linked entry move to the USB one, unless it has its own. It returns the newly
+// linked entries, by identity, whose forwards are to be moved the same way.
+func linkKnownBoards(pinned []PinnedHostKey) (map[string]string, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	known, err := loadRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	linked := map[string]string{}
+	for i := range known {
+		k := &known[i]
+		if strings.HasPrefix(k.Identity, serialIdentityPrefix) {
+			continue
+		}
+		p := slices.IndexFunc(pinned, func(p PinnedHostKey) bool { return p.Identity == k.Identity })
+		if p == -1 {
+			continue
+		}
+		u := slices.IndexFunc(known, func(u KnownBoard) bool {
+			return strings.HasPrefix(u.Identity, serialIdentityPrefix) && slices.Contains(u.HostKeys, pinned[p].Fingerprint)
+		})
+		if u == -1 || known[u].Identity == k.LinkedTo {
+			continue
+		}
+		usb := &known[u]
+		if usb.Nickname == "" {
+			usb.Nickname = k.Nickname
+		}
+		if usb.PreferredProtocol == "" {
+			usb.PreferredProtocol = k.PreferredProtocol
+		}
+		k.Nickname, k.PreferredProtocol = "", ""
+		k.LinkedTo = usb.Identity
+		linked[k.Identity] = usb.Identity
+	}
+	if len(linked) == 0 {
+		return nil, nil
+	}
+	if err := config.Save(registryFile, known); err != nil {
+		return nil, fmt.Errorf("failed to save board registry: %w", err)
+	}
+	return linked, nil
+}
@@ -0,0 +1,80 @@
+package board
+
+import (
+	"app-lab-desktop/internal/config"
+	"testing"
+
+	"github.com/arduino/arduino-app-cli/pkg/board"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestLinkKnownBoards(t *testing.T) {
+	t.Setenv("ARDUINO_APP_LAB_CONFIG_DIR", t.TempDir())
+	key := newHostKey(t)
+	usb, network, other := serialIdentityPrefix+"1234", addressIdentityPrefix+"10.0.0.2", addressIdentityPrefix+"10.0.0.3"
+
+	if err := verifyHostKey(network, "10.0.0.2:22", key); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyHostKey(other, "10.0.0.3:22", newHostKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Save(registryFile, []KnownBoard{
+		{Identity: usb, HostKeys: []string{ssh.FingerprintSHA256(key)}},
+		{Identity: network, Nickname: "lab", PreferredProtocol: board.NetworkProtocol},
+		{Identity: other, Nickname: "other"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Save(forwardsFile, map[string][]Forward{network: {{Name: "jupyter", BoardPort: 8888}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	pinned, err := PinnedHostKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, err := linkKnownBoards(pinned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(linked) != 1 || linked[network] != usb {
+		t.Fatalf("expected %s to be linked to %s, got %v", network, usb, linked)
+	}
+	if err := renameForwards(network, usb); err != nil {
+		t.Fatal(err)
+	}
+	// Linking again does nothing.
+	if linked, err = linkKnownBoards(pinned); err != nil || len(linked) != 0 {
+		t.Errorf("expected nothing to link, got %v, %v", linked, err)
+	}
+
+	// The settings of both entries are the USB one.
+	boards := Remember([]*Board{
+		{Identity: usb, Info: BoardInfo{Protocol: board.SerialProtocol}},
+		{Identity: network, Info: BoardInfo{Protocol: board.NetworkProtocol}},
+		{Identity: other, Info: BoardInfo{Protocol: board.NetworkProtocol}},
+	})
+	for _, b := range boards[:2] {
+		if b.Known.Nickname != "lab" {
+			t.Errorf("expected nickname lab for %s, got %q", b.Identity, b.Known.Nickname)
+		}
+		forwards, err := b.loadForwards()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(forwards) != 1 || forwards[0].Name != "jupyter" {
+			t.Errorf("expected the forwards to follow %s, got %+v", b.Identity, forwards)
+		}
+	}
+	if boards[2].Known.Nickname != "other" {
+		t.Errorf("expected nickname other, got %q", boards[2].Known.Nickname)
+	}
+
+	if b := Preferred(boards[:2]); b != boards[1] {
+		t.Errorf("expected the network board to be preferred, got %+v", b)
+	}
+	if b := Preferred(boards); b != nil {
+		t.Errorf("expected no preferred board among different ones, got %+v", b)
+	}
+}
@@ -0,0 +1,154 @@
+package board
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TimeSyncStatus is the state of the clock synchronization of the board with NTP.
+type TimeSyncStatus struct {
+	// Enabled is set if the board synchronizes its clock with NTP.
+	Enabled bool `json:"enabled"`
+	// Synchronized is set once the clock has been synchronized.
+	Synchronized bool   `json:"synchronized"`
+	Timezone     string `json:"timezone"`
+	LocalTime    string `json:"localTime"`
+}
+
+func (b *Board) GetTimezone(ctx context.Context) (string, error) {
+	props, err := b.timedateProperties(ctx)
+	if err != nil {
+		return "", err
+	}
+	return props["Timezone"], nil
+}
+
+// ListTimezones returns the IANA timezones known by the board, e.g. "Europe/Rome".
+func (b *Board) ListTimezones(ctx context.Context) ([]string, error) {
+	out, err := b.output(ctx, "timedatectl", "list-timezones")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list timezones: %w", err)
+	}
+	return strings.Fields(out), nil
+}
+
+func (b *Board) SetTimezone(ctx context.Context, timezone string) error {
+	timezones, err := b.ListTimezones(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(timezones, timezone) {
+		return fmt.Errorf("invalid timezone %q", timezone)
+	}
+	if _, err := b.runPrivileged(ctx, "timedatectl", "set-timezone", timezone); err != nil {
+		return fmt.Errorf("failed to set timezone: %w", err)
+	}
+	return nil
+}
+
+// GetLocale returns the system locale of the board, e.g. "en_US.UTF-8".
+func (b *Board) GetLocale(ctx context.Context) (string, error) {
+	out, err := b.output(ctx, "localectl", "status")
+	if err != nil {
+		return "", fmt.Errorf("failed to get locale: %w", err)
+	}
+	return parseSystemLocale(out), nil
+}
+
+// ListLocales returns the locales generated on the board, that can be set.
+func (b *Board) ListLocales(ctx context.Context) ([]string, error) {
+	out, err := b.output(ctx, "localectl", "list-locales")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locales: %w", err)
+	}
+	return strings.Fields(out), nil
+}
+
+func (b *Board) SetLocale(ctx context.Context, locale string) error {
+	locales, err := b.ListLocales(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(locales, locale) {
+		return fmt.Errorf("invalid locale %q", locale)
+	}
+	if _, err := b.runPrivileged(ctx, "localectl", "set-locale", "LANG="+locale); err != nil {
+		return fmt.Errorf("failed to set locale: %w", err)
+	}
+	return nil
+}
+
+func (b *Board) GetTimeSyncStatus(ctx context.Context) (TimeSyncStatus, error) {
+	props, err := b.timedateProperties(ctx)
+	if err != nil {
+		return TimeSyncStatus{}, err
+	}
+	return TimeSyncStatus{
+		Enabled:      props["NTP"] == "yes",
+		Synchronized: props["NTPSynchronized"] == "yes",
+		Timezone:     props["Timezone"],
+		LocalTime:    props["TimeUSec"],
+	}, nil
+}
+
+// SetTimeSync enables or disables the clock synchronization with NTP.
+func (b *Board) SetTimeSync(ctx context.Context, enabled bool) error {
+	if _, err := b.runPrivileged(ctx, "timedatectl", "set-ntp", fmt.Sprint(enabled)); err != nil {
+		return fmt.Errorf("failed to set time sync: %w", err)
+	}
+	return nil
+}
+
+func (b *Board) timedateProperties(ctx context.Context) (map[string]string, error) {
+	out, err := b.output(ctx, "timedatectl", "show")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time settings: %w", err)
+	}
+	return parseProperties(out), nil
+}
+
+// output runs a command and returns its standard output, failing on a non-zero exit code.
+func (b *Board) output(ctx context.Context, name string, args ...string) (string, error) {
+	res, err := b.RunCommand(ctx, name, args)
+	if err != nil {
+		return "", err
+	}
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("%s exited with %d: %s", name, res.ExitCode, strings.TrimSpace(res.Stderr))
+	}
+	return res.Stdout, nil
+}
+
+// parseProperties parses the Key=value lines of the systemd show commands.
+func parseProperties(out string) map[string]string {
+	props := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[key] = value
+		}
+	}
+	return props
+}
+
+// parseSystemLocale returns the LANG of the "System Locale:" lines of localectl status,
+// the other variables being on the following lines.
+func parseSystemLocale(out string) string {
+	inSystemLocale := false
+	for _, line := range strings.Split(out, "\n") {
+		label, value, hasLabel := strings.Cut(line, ":")
+		if hasLabel {
+			inSystemLocale = strings.TrimSpace(label) == "System Locale"
+		} else {
+			value = line
+		}
+		if !inSystemLocale {
+			continue
+		}
+		if lang, ok := strings.CutPrefix(strings.TrimSpace(value), "LANG="); ok {
+			return lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package board
+
+import "testing"
+
+func TestParseSystemLocale(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{
+			name:     "single variable",
+			output:   "   System Locale: LANG=en_US.UTF-8\n       VC Keymap: us\n      X11 Layout: us\n",
+			expected: "en_US.UTF-8",
+		},
+		{
+			name:     "several variables",
+			output:   "   System Locale: LC_TIME=it_IT.UTF-8\n                  LANG=de_DE.UTF-8\n       VC Keymap: de\n",
+			expected: "de_DE.UTF-8",
+		},
+		{
+			name:     "not set",
+			output:   "   System Locale: n/a\n       VC Keymap: us\n      X11 Layout: LANG=us\n",
+			expected: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSystemLocale(tt.output); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package board
+
+import (
+	"app-lab-desktop/internal/config"
+	"app-lab-desktop/internal/sshconn"
+	"app-lab-desktop/internal/tunnel"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/arduino/arduino-app-cli/pkg/board"
+)
+
+const (
+	// OriginManual marks the boards registered by the user instead of discovered.
+	OriginManual = "manual"
+
+	manualBoardsFile = "manual-boards.json"
+	unoQBoardName    = "Arduino UNO Q"
+)
+
+// ManualBoard is a network board registered by address, for the networks where the
+// discovery cannot find it.
+type ManualBoard struct {
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	Name    string `json:"name,omitempty"`
+}
+
+var manualBoardsMu sync.Mutex
+
+func ListManualBoards() ([]ManualBoard, error) {
+	manualBoardsMu.Lock()
+	defer manualBoardsMu.Unlock()
+	return loadManualBoards()
+}
+
+// AddManualBoard probes the board at address:port over SSH and registers it. When
+// name is empty, the name is read from the board, which requires the password.
+func AddManualBoard(ctx context.Context, address string, port int, name string, password string) (*Board, error) {
+	if address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+	if port == 0 {
+		port = sshconn.DefaultPort
+	}
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", port)
+	}
+
+	probedName, err := ProbeNetworkBoard(ctx, address, port, password)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = probedName
+	}
+
+	manualBoardsMu.Lock()
+	defer manualBoardsMu.Unlock()
+
+	entries, err := loadManualBoards()
+	if err != nil {
+		return nil, err
+	}
+	entry := ManualBoard{Address: address, Port: port, Name: name}
+	entries = slices.DeleteFunc(entries, func(m ManualBoard) bool {
+		return m.Address == address && m.Port == port
+	})
+	entries = append(entries, entry)
+	if err := config.Save(manualBoardsFile, entries); err != nil {
+		return nil, fmt.Errorf("failed to save manual boards: %w", err)
+	}
+	return newManualBoard(entry), nil
+}
+
+// RemoveManualBoard unregisters the manual board with the given id or address.
+func RemoveManualBoard(idOrAddress string) error {
+	manualBoardsMu.Lock()
+	defer manualBoardsMu.Unlock()
+
+	entries, err := loadManualBoards()
+	if err != nil {
+		return err
+	}
+	n := len(entries)
+	entries = slices.DeleteFunc(entries, func(m ManualBoard) bool {
+		return newManualBoard(m).Id == idOrAddress ||
+			m.Address == idOrAddress ||
+			sshAddr(m.Address, m.Port) == idOrAddress
+	})
+	if len(entries) == n {
+		return fmt.Errorf("manual board %s not found", idOrAddress)
+	}
+	if err := config.Save(manualBoardsFile, entries); err != nil {
+		return fmt.Errorf("failed to save manual boards: %w", err)
+	}
+	return nil
+}
+
+func loadManualBoards() ([]ManualBoard, error) {
+	var entries []ManualBoard
+	if err := config.Load(manualBoardsFile, &entries); err != nil {
+		return nil, fmt.Errorf("failed to load manual boards: %w", err)
+	}
+	return entries, nil
+}
+
+func newManualBoard(m ManualBoard) *Board {
+	// The id only depends on where the board is, so that it is stable across
+	// restarts even if the board name changes.
+	id, _ := hashStruct(struct {
+		Origin  string
+		Address string
+		Port    int
+	}{OriginManual, m.Address, m.Port})
+
+	return &Board{
+		Id:       id,
+		Identity: manualIdentity(m),
+		Info: BoardInfo{
+			Protocol:   board.NetworkProtocol,
+			Address:    m.Address,
+			CustomName: m.Name,
+			BoardName:  unoQBoardName,
+		},
+		Origin:  OriginManual,
+		sshPort: m.Port,
+		tunnels: tunnel.NewRegistry(),
+		conn:    NoopConn(),
+	}
+}
+
+// manualIdentity matches the identity of the same board when discovered, unless it
+// is reached on a custom port.
+func manualIdentity(m ManualBoard) string {
+	if m.Port == sshconn.DefaultPort {
+		return addressIdentityPrefix + m.Address
+	}
+	return addressIdentityPrefix + sshAddr(m.Address, m.Port)
+}
+
+// WithManualBoards appends the manual boards to the discovered ones. A manual board
+// that has been discovered too is skipped, the discovered one has fresher info.
+func WithManualBoards(boards []*Board) []*Board {
+	manualBoardsMu.Lock()
+	entries, err := loadManualBoards()
+	manualBoardsMu.Unlock()
+	if err != nil {
+		slog.Error("failed to load manual boards", "err", err)
+		return boards
+	}
+
+	result := slices.Clone(boards)
+	for _, m := range entries {
+		discovered := m.Port == sshconn.DefaultPort && slices.ContainsFunc(boards, func(b *Board) bool {
+			return b.Info.Protocol == board.NetworkProtocol && b.Info.Address == m.Address
+		})
+		if !discovered {
+			result = append(result, newManualBoard(m))
+		}
+	}
+	return result
+}
@@ -17,17 +17,25 @@ import (
 	"github.com/arduino/go-paths-helper"
 	"github.com/codeclysm/extract/v4"
 	"github.com/sirupsen/logrus"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 var toolsInstalled = false
 var toolsInstallLock sync.Mutex
 
 func GetBoards(ctx context.Context) ([]*Board, error) {
-	toolsInstallLock.Lock()
-	initalized := toolsInstalled
-	toolsInstallLock.Unlock()
-	if !initalized {
+	boards, err := detectBoards(ctx)
+	if err != nil {
+		return nil, err
+	}
+	boards = Enrich(boards)
+	if len(boards) == 0 {
+		return nil, fmt.Errorf("no boards found for FQBN %s", arduinoQFqbn)
+	}
+	return boards, nil
+}
+
+func detectBoards(ctx context.Context) ([]*Board, error) {
+	if !isToolingInstalled() {
 		return nil, errors.New("detection tools not installed")
 	}
 
@@ -35,15 +43,12 @@ func GetBoards(ctx context.Context) ([]*Board, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failing to get board from FQBN: %w", err)
 	}
-	if len(boards) == 0 {
-		return nil, fmt.Errorf("no boards found for FQBN %s", arduinoQFqbn)
-	}
 
 	var result []*Board
 	for _, b := range boards {
 		board, err := New(&b)
 		if err != nil {
-			runtime.LogErrorf(ctx, "failed to create board instance: %v", err)
+			slog.Error("failed to create board instance", "err", err)
 			continue
 		}
 		result = append(result, board)
@@ -56,7 +61,8 @@ func GetSbcBoard(ctx context.Context) (*Board, error) {
 		return nil, fmt.Errorf("not running on SBC")
 	}
 
-	boards, err := GetBoards(ctx)
+	// Manual boards are left out, the SBC board is the local one.
+	boards, err := detectBoards(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get boards: %w", err)
 	}
@@ -71,6 +77,12 @@ func GetSbcBoard(ctx context.Context) (*Board, error) {
 	return b, nil
 }
 
+func isToolingInstalled() bool {
+	toolsInstallLock.Lock()
+	defer toolsInstallLock.Unlock()
+	return toolsInstalled
+}
+
 // InstallToolingIfMissing checks if the required discovery tools are installed, and if not,
 // it installs them using the embedded resources.
 func InstallToolingIfMissing(ctx context.Context) error {
@@ -0,0 +1,56 @@
+package board
+
+import (
+	"app-lab-desktop/internal/sshconn"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// serviceNamePattern matches the systemd unit names, with an optional type suffix.
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9@._:-]*$`)
+
+// Reboot asks systemd to reboot the board, without waiting for it to go down.
+func (b *Board) Reboot(ctx context.Context) error {
+	return b.systemctl(ctx, "--no-block", "reboot")
+}
+
+// Shutdown asks systemd to power off the board, without waiting for it to go down.
+func (b *Board) Shutdown(ctx context.Context) error {
+	return b.systemctl(ctx, "--no-block", "poweroff")
+}
+
+// RestartService restarts the systemd service and waits for it to be started again.
+func (b *Board) RestartService(ctx context.Context, name string) error {
+	if !serviceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid service name %q", name)
+	}
+	return b.systemctl(ctx, "restart", name)
+}
+
+// systemctl runs systemctl with the privileges it needs, see runPrivileged.
+func (b *Board) systemctl(ctx context.Context, args ...string) error {
+	_, err := b.runPrivileged(ctx, "systemctl", args...)
+	return err
+}
+
+// runPrivileged runs a command changing the system settings and returns its output.
+// It runs directly when the board user is root, with sudo otherwise, provided that no
+// password is required. The command runs once, its errors are the ones reported.
+func (b *Board) runPrivileged(ctx context.Context, name string, args ...string) (string, error) {
+	words := []string{sshconn.Quote(name)}
+	for _, a := range args {
+		words = append(words, sshconn.Quote(a))
+	}
+	cmdline := strings.Join(words, " ")
+	script := `if [ "$(id -u)" -eq 0 ]; then exec ` + cmdline + `; else exec sudo -n ` + cmdline + `; fi`
+	res, err := b.RunCommand(ctx, "sh", []string{"-c", script})
+	if err != nil {
+		return "", fmt.Errorf("%s %s failed: %w", name, strings.Join(args, " "), err)
+	}
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("%s %s failed: %s", name, strings.Join(args, " "), strings.TrimSpace(res.Stderr))
+	}
+	return res.Stdout, nil
+}
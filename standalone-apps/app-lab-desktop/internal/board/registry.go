@@ -0,0 +1,270 @@
+package board
+
+import (
+	"app-lab-desktop/internal/config"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/arduino/arduino-app-cli/pkg/board"
+)
+
+const (
+	registryFile = "boards.json"
+	// lastSeenResolution limits how often the registry is written while the boards
+	// are listed over and over.
+	lastSeenResolution = time.Minute
+)
+
+// KnownBoard is the registry entry of a board that has been seen at least once.
+type KnownBoard struct {
+	Identity     string    `json:"identity"`
+	Nickname     string    `json:"nickname,omitempty"`
+	BoardName    string    `json:"boardName,omitempty"`
+	CustomName   string    `json:"customName,omitempty"`
+	Serial       string    `json:"serial,omitempty"`
+	LastSeen     time.Time `json:"lastSeen"`
+	LastProtocol string    `json:"lastProtocol"`
+	LastAddress  string    `json:"lastAddress,omitempty"`
+	// PreferredProtocol is the protocol to connect with when the board is reachable
+	// over both USB and network.
+	PreferredProtocol string `json:"preferredProtocol,omitempty"`
+	// HostKeys are the fingerprints of the SSH host keys of a board, read over USB.
+	HostKeys []string `json:"hostKeys,omitempty"`
+	// LinkedTo is set on the network entry of a board also known over USB, to the
+	// identity of the USB entry, which holds the settings of both.
+	LinkedTo string `json:"linkedTo,omitempty"`
+	// KeyInstalled is set when the app SSH key has been installed on the board.
+	KeyInstalled bool `json:"keyInstalled,omitempty"`
+}
+
+var registryMu sync.Mutex
+
+// Enrich completes the discovered boards with the local state: the manual boards and
+// the registry entries.
+func Enrich(boards []*Board) []*Board {
+	return Remember(WithManualBoards(boards))
+}
+
+// Remember records the boards in the registry and returns copies of them enriched
+// with their registry entry.
+func Remember(boards []*Board) []*Board {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	known, err := loadRegistry()
+	if err != nil {
+		slog.Error("failed to load board registry", "err", err)
+	}
+
+	changed := false
+	now := time.Now()
+	result := make([]*Board, len(boards))
+	for i, b := range boards {
+		j := slices.IndexFunc(known, func(k KnownBoard) bool { return k.Identity == b.Identity })
+		if j == -1 {
+			known = append(known, KnownBoard{Identity: b.Identity})
+			j = len(known) - 1
+		}
+		if k := &known[j]; k.update(b, now) {
+			changed = true
+		}
+
+		entry := known[j]
+		if s := settingsIndex(known, b.Identity); s != j {
+			entry.Nickname = known[s].Nickname
+			entry.PreferredProtocol = known[s].PreferredProtocol
+		}
+		result[i] = b.Clone()
+		result[i].Known = &entry
+	}
+
+	if changed && err == nil {
+		if err := config.Save(registryFile, known); err != nil {
+			slog.Error("failed to save board registry", "err", err)
+		}
+	}
+	return result
+}
+
+func (k *KnownBoard) update(b *Board, now time.Time) bool {
+	changed := false
+	set := func(field *string, value string) {
+		if *field != value {
+			*field = value
+			changed = true
+		}
+	}
+	set(&k.BoardName, b.Info.BoardName)
+	set(&k.CustomName, b.Info.CustomName)
+	set(&k.Serial, b.Info.Serial)
+	set(&k.LastProtocol, b.Info.Protocol)
+	if b.Info.Address != "" {
+		set(&k.LastAddress, b.Info.Address)
+	}
+	if now.Sub(k.LastSeen) >= lastSeenResolution {
+		k.LastSeen = now
+		changed = true
+	}
+	return changed
+}
+
+// KnownBoards returns every board of the registry, including the ones that are not
+// reachable anymore.
+func KnownBoards() ([]KnownBoard, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return loadRegistry()
+}
+
+// DisplayName returns the name the user knows the board by: its nickname, its
+// custom name or its model, in this order.
+func (b *Board) DisplayName() string {
+	switch {
+	case b.Known != nil && b.Known.Nickname != "":
+		return b.Known.Nickname
+	case b.Info.CustomName != "":
+		return b.Info.CustomName
+	case b.Info.BoardName != "":
+		return b.Info.BoardName
+	default:
+		return b.Identity
+	}
+}
+
+// SetNickname sets the local name of a known board, an empty nickname removes it.
+func SetNickname(identity string, nickname string) error {
+	return updateSettings(identity, func(k *KnownBoard) error {
+		k.Nickname = nickname
+		return nil
+	})
+}
+
+// SetPreferredProtocol sets the protocol to use for a known board reachable on several ones.
+func SetPreferredProtocol(identity string, protocol string) error {
+	return updateSettings(identity, func(k *KnownBoard) error {
+		switch protocol {
+		case "", board.SerialProtocol, board.NetworkProtocol:
+		default:
+			return fmt.Errorf("unsupported protocol %q", protocol)
+		}
+		k.PreferredProtocol = protocol
+		return nil
+	})
+}
+
+// Preferred returns the board to use among boards reaching the same physical board
+// over different protocols: the one on its preferred protocol, the first one when it
+// has none. It returns nil if the boards are not all the same one.
+func Preferred(boards []*Board) *Board {
+	if len(boards) == 0 {
+		return nil
+	}
+	for _, b := range boards[1:] {
+		if b.linkedIdentity() != boards[0].linkedIdentity() {
+			return nil
+		}
+	}
+	for _, b := range boards {
+		if b.Known != nil && b.Info.Protocol == b.Known.PreferredProtocol {
+			return b
+		}
+	}
+	return boards[0]
+}
+
+// linkedIdentity returns the identity of the registry entry holding the settings of
+// the listed board, see KnownBoard.LinkedTo.
+func (b *Board) linkedIdentity() string {
+	if b.Known != nil && b.Known.LinkedTo != "" {
+		return b.Known.LinkedTo
+	}
+	return b.Identity
+}
+
+// settingsIdentity returns the identity of the registry entry holding the settings of
+// the board with the given identity, up to date unlike the one of a listed board.
+func settingsIdentity(identity string) string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	known, err := loadRegistry()
+	if err != nil {
+		slog.Error("failed to load board registry", "err", err)
+		return identity
+	}
+	if i := settingsIndex(known, identity); i != -1 {
+		return known[i].Identity
+	}
+	return identity
+}
+
+// settingsIndex returns the index of the entry holding the settings of identity: the
+// USB entry it is linked to, or its own. It returns -1 if identity is not known.
+func settingsIndex(known []KnownBoard, identity string) int {
+	i := knownIndex(known, identity)
+	if i != -1 && known[i].LinkedTo != "" {
+		if j := knownIndex(known, known[i].LinkedTo); j != -1 {
+			return j
+		}
+	}
+	return i
+}
+
+func knownIndex(known []KnownBoard, identity string) int {
+	return slices.IndexFunc(known, func(k KnownBoard) bool { return k.Identity == identity })
+}
+
+// ForgetBoard removes a board from the registry.
+func ForgetBoard(identity string) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	known, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+	n := len(known)
+	known = slices.DeleteFunc(known, func(k KnownBoard) bool { return k.Identity == identity })
+	if len(known) == n {
+		return fmt.Errorf("board %s is not known", identity)
+	}
+	return config.Save(registryFile, known)
+}
+
+func updateKnownBoard(identity string, update func(*KnownBoard) error) error {
+	return updateEntry(identity, knownIndex, update)
+}
+
+// updateSettings updates the entry holding the settings of the board, see settingsIndex.
+func updateSettings(identity string, update func(*KnownBoard) error) error {
+	return updateEntry(identity, settingsIndex, update)
+}
+
+func updateEntry(identity string, index func([]KnownBoard, string) int, update func(*KnownBoard) error) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	known, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+	i := index(known, identity)
+	if i == -1 {
+		return fmt.Errorf("board %s is not known", identity)
+	}
+	if err := update(&known[i]); err != nil {
+		return err
+	}
+	return config.Save(registryFile, known)
+}
+
+func loadRegistry() ([]KnownBoard, error) {
+	var known []KnownBoard
+	if err := config.Load(registryFile, &known); err != nil {
+		return nil, fmt.Errorf("failed to load board registry: %w", err)
+	}
+	return known, nil
+}
@@ -0,0 +1,148 @@
+package board
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resourcesScript reads the resource usage in one round trip. docker stats waits for
+// two samples of the containers, so it takes about two seconds.
+const resourcesScript = `s() { echo "` + sectionMarker + `$1"; }
+s stat; head -n 1 /proc/stat 2>/dev/null
+s loadavg; cat /proc/loadavg 2>/dev/null
+s meminfo; cat /proc/meminfo 2>/dev/null
+s thermal; ` + thermalScript + `
+s cpufreq; cat /sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_cur_freq 2>/dev/null
+s docker; docker stats --no-stream --format '{{.Name}}	{{.CPUPerc}}	{{.MemUsage}}' 2>/dev/null
+true
+`
+
+// CPUTimes are the cumulated CPU times of the board, in clock ticks: the usage over a
+// period is the busy time over the total time elapsed between two readings.
+type CPUTimes struct {
+	Busy  uint64 `json:"busy"`
+	Total uint64 `json:"total"`
+}
+
+// ResourceUsage is a reading of the resources used on the board.
+type ResourceUsage struct {
+	Time              time.Time        `json:"time"`
+	CPU               CPUTimes         `json:"cpu"`
+	Load              [3]float64       `json:"load"`
+	Memory            MemoryInfo       `json:"memory"`
+	Temperatures      []Temperature    `json:"temperatures"`
+	CPUFrequenciesMHz []float64        `json:"cpuFrequenciesMHz"`
+	Containers        []ContainerUsage `json:"containers"`
+}
+
+type ContainerUsage struct {
+	Name             string  `json:"name"`
+	CPUPercent       float64 `json:"cpuPercent"`
+	MemoryBytes      uint64  `json:"memoryBytes"`
+	MemoryLimitBytes uint64  `json:"memoryLimitBytes"`
+}
+
+// ReadResources reads the CPU, memory, temperature and container usage of the board.
+func (b *Board) ReadResources(ctx context.Context) (ResourceUsage, error) {
+	res, err := b.RunCommand(ctx, "sh", []string{"-c", resourcesScript})
+	if err != nil {
+		return ResourceUsage{}, fmt.Errorf("failed to read resources: %w", err)
+	}
+	if res.ExitCode != 0 {
+		return ResourceUsage{}, fmt.Errorf("failed to read resources: exit status %d: %s", res.ExitCode, strings.TrimSpace(res.Stderr))
+	}
+	usage := parseResources(res.Stdout)
+	usage.Time = time.Now()
+	return usage, nil
+}
+
+func parseResources(out string) ResourceUsage {
+	sections := splitSections(out)
+	usage := ResourceUsage{
+		CPU:               parseCPUTimes(sections["stat"]),
+		Memory:            parseMeminfo(sections["meminfo"]),
+		Temperatures:      parseThermal(sections["thermal"]),
+		CPUFrequenciesMHz: []float64{},
+		Containers:        parseDockerStats(sections["docker"]),
+	}
+	if f := strings.Fields(sections["loadavg"]); len(f) >= 3 {
+		for i := range usage.Load {
+			usage.Load[i], _ = strconv.ParseFloat(f[i], 64)
+		}
+	}
+	for _, line := range strings.Fields(sections["cpufreq"]) {
+		if khz, err := strconv.ParseFloat(line, 64); err == nil {
+			usage.CPUFrequenciesMHz = append(usage.CPUFrequenciesMHz, khz/1000)
+		}
+	}
+	return usage
+}
+
+// parseCPUTimes parses the aggregated "cpu" line of /proc/stat: user, nice, system,
+// idle, iowait, irq, softirq and steal times. The guest times are already counted
+// in the user ones.
+func parseCPUTimes(s string) CPUTimes {
+	f := strings.Fields(s)
+	if len(f) < 5 || f[0] != "cpu" {
+		return CPUTimes{}
+	}
+	var t CPUTimes
+	for i, v := range f[1:min(len(f), 9)] {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return CPUTimes{}
+		}
+		t.Total += n
+		// idle and iowait
+		if i != 3 && i != 4 {
+			t.Busy += n
+		}
+	}
+	return t
+}
+
+// parseDockerStats parses the "<name>\t<cpu>%\t<used> / <limit>" lines of docker stats.
+func parseDockerStats(s string) []ContainerUsage {
+	containers := []ContainerUsage{}
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) != 3 {
+			continue
+		}
+		c := ContainerUsage{Name: f[0]}
+		c.CPUPercent, _ = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(f[1]), "%"), 64)
+		used, limit, _ := strings.Cut(f[2], "/")
+		c.MemoryBytes = parseSize(used)
+		c.MemoryLimitBytes = parseSize(limit)
+		containers = append(containers, c)
+	}
+	return containers
+}
+
+// sizeUnits are the units of the docker sizes, the longest suffixes first.
+var sizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseSize parses a human readable size like "12.5MiB", returning 0 if invalid.
+func parseSize(s string) uint64 {
+	s = strings.TrimSpace(s)
+	for _, u := range sizeUnits {
+		if v, ok := strings.CutSuffix(s, u.suffix); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return 0
+			}
+			return uint64(n * u.factor)
+		}
+	}
+	return 0
+}
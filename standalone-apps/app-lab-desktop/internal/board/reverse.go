@@ -0,0 +1,23 @@
+package board
+
+import (
+	"app-lab-desktop/internal/tunnel"
+	"context"
+	"fmt"
+)
+
+// OpenReverseTunnel makes boardPort, on the board localhost, reach localPort on the
+// host, reusing the reverse tunnel if it is already open.
+func (b *Board) OpenReverseTunnel(ctx context.Context, tag string, boardPort int, localPort int) (tunnel.Info, error) {
+	if boardPort <= 0 || boardPort > 65535 {
+		return tunnel.Info{}, fmt.Errorf("invalid board port %d", boardPort)
+	}
+	if localPort <= 0 || localPort > 65535 {
+		return tunnel.Info{}, fmt.Errorf("invalid local port %d", localPort)
+	}
+	info, err := b.tunnels.OpenReverse(ctx, b.Conn(), tag, boardPort, localPort)
+	if err != nil {
+		return tunnel.Info{}, fmt.Errorf("failed to open reverse tunnel: %w", err)
+	}
+	return info, nil
+}
@@ -0,0 +1,94 @@
+package board
+
+import (
+	"app-lab-desktop/internal/sshconn"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/arduino/arduino-app-cli/pkg/board"
+	appssh "github.com/arduino/arduino-app-cli/pkg/board/remote/ssh"
+	"golang.org/x/crypto/ssh"
+)
+
+// dialSSH connects to a network board with the app own SSH client, that unlike the
+// arduino-app-cli one supports a port other than the default one, key authentication
+// and host key pinning.
+func dialSSH(ctx context.Context, identity string, address string, port int, auth []ssh.AuthMethod) (*sshconn.Conn, error) {
+	config := &ssh.ClientConfig{
+		User:            sshconn.DefaultUser,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback(identity),
+	}
+
+	conn, err := sshconn.Dial(ctx, sshAddr(address, port), config)
+	if err != nil {
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return nil, fmt.Errorf("%w: %w", appssh.ErrAuthFailed, err)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// sshAuth returns the authentication methods for the password, or for the app key
+// when the password is empty.
+func sshAuth(password string) ([]ssh.AuthMethod, error) {
+	if password == "" {
+		signer, err := loadAppKey()
+		if err != nil {
+			return nil, err
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+	}
+
+	return []ssh.AuthMethod{
+		ssh.Password(password),
+		ssh.KeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range answers {
+				answers[i] = password
+			}
+			return answers, nil
+		}),
+	}, nil
+}
+
+func sshAddr(address string, port int) string {
+	if port == 0 {
+		port = sshconn.DefaultPort
+	}
+	return net.JoinHostPort(address, strconv.Itoa(port))
+}
+
+// ProbeNetworkBoard checks that an SSH server answers at address:port and returns
+// the name of the board, that can only be read when the password is given.
+// The host key is checked against the one pinned for the address, and pinned on
+// first use, so that another host answering there is reported as a mismatch.
+func ProbeNetworkBoard(ctx context.Context, address string, port int, password string) (string, error) {
+	identity := manualIdentity(ManualBoard{Address: address, Port: port})
+	if password == "" {
+		if err := checkHostKey(ctx, identity, sshAddr(address, port)); err != nil {
+			return "", fmt.Errorf("failed to probe board: %w", err)
+		}
+		return "", nil
+	}
+
+	auth, err := sshAuth(password)
+	if err != nil {
+		return "", err
+	}
+	conn, err := dialSSH(ctx, identity, address, port, auth)
+	if err != nil {
+		return "", fmt.Errorf("failed to probe board: %w", err)
+	}
+	defer conn.Close()
+
+	name, err := board.GetCustomName(ctx, conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read board name: %w", err)
+	}
+	return name, nil
+}
@@ -0,0 +1,265 @@
+package board
+
+import (
+	"app-lab-desktop/internal/config"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	appKeyFile         = "id_ed25519"
+	authorizedKeysPath = "/home/arduino/.ssh/authorized_keys"
+)
+
+var (
+	ErrNoAppKey = errors.New("app SSH key not generated")
+	appKeyMu    sync.Mutex
+)
+
+// HasAppKey reports whether the app SSH key has been generated.
+func HasAppKey() bool {
+	_, err := loadAppKey()
+	return err == nil
+}
+
+// AppPublicKey returns the app public key in authorized_keys format, generating the
+// key pair on first use.
+func AppPublicKey() (string, error) {
+	appKeyMu.Lock()
+	defer appKeyMu.Unlock()
+
+	signer, err := loadAppKeyLocked()
+	if errors.Is(err, ErrNoAppKey) {
+		signer, err = generateAppKey()
+	}
+	if err != nil {
+		return "", err
+	}
+	return authorizedKey(signer), nil
+}
+
+func loadAppKey() (ssh.Signer, error) {
+	appKeyMu.Lock()
+	defer appKeyMu.Unlock()
+	return loadAppKeyLocked()
+}
+
+func loadAppKeyLocked() (ssh.Signer, error) {
+	p, err := config.Path(appKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoAppKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read app SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse app SSH key: %w", err)
+	}
+	return signer, nil
+}
+
+func generateAppKey() (ssh.Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate app SSH key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, keyComment())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode app SSH key: %w", err)
+	}
+	if err := config.WriteFile(appKeyFile, pem.EncodeToMemory(block)); err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(priv)
+}
+
+func keyComment() string {
+	host, _ := os.Hostname()
+	return "arduino-app-lab@" + host
+}
+
+func authorizedKey(signer ssh.Signer) string {
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	return line + " " + keyComment()
+}
+
+// InstallAppKey appends the app public key to the authorized_keys of the board, over
+// an established connection such as ADB or a password session. The file is edited
+// through the remote file system, that behaves the same on every connection.
+func InstallAppKey(ctx context.Context, conn remote.RemoteConn) error {
+	key, err := AppPublicKey()
+	if err != nil {
+		return err
+	}
+
+	content, err := readAuthorizedKeys(conn)
+	if err != nil {
+		return err
+	}
+	content, changed := addAuthorizedKey(content, key)
+	if !changed {
+		return nil
+	}
+
+	if err := conn.MkDirAll(path.Dir(authorizedKeysPath)); err != nil {
+		return fmt.Errorf("failed to create .ssh dir: %w", err)
+	}
+	if err := writeAuthorizedKeys(ctx, conn, content); err != nil {
+		return fmt.Errorf("failed to install app SSH key: %w", err)
+	}
+	return nil
+}
+
+// RemoveAppKey removes the app public key from the authorized_keys of the board.
+func RemoveAppKey(ctx context.Context, conn remote.RemoteConn) error {
+	signer, err := loadAppKey()
+	if err != nil {
+		return err
+	}
+
+	content, err := readAuthorizedKeys(conn)
+	if err != nil {
+		return err
+	}
+	content, changed := removeAuthorizedKey(content, publicKeyBlob(signer))
+	if !changed {
+		return nil
+	}
+	if err := writeAuthorizedKeys(ctx, conn, content); err != nil {
+		return fmt.Errorf("failed to remove app SSH key: %w", err)
+	}
+	return nil
+}
+
+// IsAppKeyInstalled reports whether the app public key is authorized by the board.
+func IsAppKeyInstalled(conn remote.RemoteConn) (bool, error) {
+	signer, err := loadAppKey()
+	if errors.Is(err, ErrNoAppKey) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	content, err := readAuthorizedKeys(conn)
+	if err != nil {
+		return false, err
+	}
+	_, found := removeAuthorizedKey(content, publicKeyBlob(signer))
+	return found, nil
+}
+
+// InstallKey installs the app key on the board, so that later network connections
+// do not need the password.
+func (b *Board) InstallKey(ctx context.Context) error {
+	if err := InstallAppKey(ctx, b.Conn()); err != nil {
+		return err
+	}
+	b.setKeyInstalled(true)
+	return nil
+}
+
+// RemoveKey removes the app key from the board.
+func (b *Board) RemoveKey(ctx context.Context) error {
+	if err := RemoveAppKey(ctx, b.Conn()); err != nil {
+		return err
+	}
+	b.setKeyInstalled(false)
+	return nil
+}
+
+func (b *Board) setKeyInstalled(installed bool) {
+	err := updateKnownBoard(b.Identity, func(k *KnownBoard) error {
+		k.KeyInstalled = installed
+		return nil
+	})
+	if err != nil {
+		slog.Warn("failed to record app key in the board registry", "err", err)
+	}
+}
+
+func readAuthorizedKeys(conn remote.RemoteConn) (string, error) {
+	if _, err := conn.Stats(authorizedKeysPath); err != nil {
+		// The file does not exist yet.
+		return "", nil
+	}
+	r, err := conn.ReadFile(authorizedKeysPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read authorized keys: %w", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read authorized keys: %w", err)
+	}
+	return string(data), nil
+}
+
+func writeAuthorizedKeys(ctx context.Context, conn remote.RemoteConn, content string) error {
+	if err := conn.WriteFile(strings.NewReader(content), authorizedKeysPath); err != nil {
+		return err
+	}
+	// sshd ignores the keys if the file is writable by others, it may not be the
+	// case with the default permissions of the remote file system.
+	if err := conn.GetCmd("chmod", "600", authorizedKeysPath).Run(ctx); err != nil {
+		slog.Warn("failed to set authorized keys permissions", "err", err)
+	}
+	return nil
+}
+
+// addAuthorizedKey appends the key line to the authorized_keys content, unless the
+// same key is already there.
+func addAuthorizedKey(content string, key string) (string, bool) {
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return content, false
+	}
+	if _, found := removeAuthorizedKey(content, fields[1]); found {
+		return content, false
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + key + "\n", true
+}
+
+// removeAuthorizedKey removes the lines of the authorized_keys content holding the
+// key with the given base64 blob. Keys are matched without their comment, that
+// depends on the host name.
+func removeAuthorizedKey(content string, blob string) (string, bool) {
+	var kept []string
+	found := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if slices.Contains(strings.Fields(line), blob) {
+			found = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, ""), found
+}
+
+// publicKeyBlob returns the base64 part of the authorized_keys line.
+func publicKeyBlob(signer ssh.Signer) string {
+	fields := strings.Fields(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	return fields[1]
+}
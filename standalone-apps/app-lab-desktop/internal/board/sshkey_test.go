@@ -0,0 +1,35 @@
+package board
+
+import (
+	"testing"
+)
+
+func TestAuthorizedKeys(t *testing.T) {
+	const (
+		other = "ssh-rsa AAAAother user@laptop\n"
+		key   = "ssh-ed25519 AAAAapp arduino-app-lab@host"
+	)
+
+	content, changed := addAuthorizedKey(other, key)
+	if !changed || content != other+key+"\n" {
+		t.Fatalf("unexpected content after add: %q", content)
+	}
+
+	// Same key installed from another host, with a different comment.
+	if _, changed := addAuthorizedKey(content, "ssh-ed25519 AAAAapp arduino-app-lab@other"); changed {
+		t.Errorf("expected the key to be already installed")
+	}
+
+	if content, changed := addAuthorizedKey("ssh-rsa AAAAother user@laptop", key); !changed || content != other+key+"\n" {
+		t.Errorf("unexpected content after add without trailing newline: %q", content)
+	}
+
+	content, found := removeAuthorizedKey(content, "AAAAapp")
+	if !found || content != other {
+		t.Errorf("unexpected content after remove: %q", content)
+	}
+
+	if _, found := removeAuthorizedKey(content, "AAAAapp"); found {
+		t.Errorf("expected the key to be already removed")
+	}
+}
@@ -0,0 +1,280 @@
+package board
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sectionMarker starts each section of the system info script output.
+const sectionMarker = "@@@ "
+
+// thermalScript prints the type and the temperature, in millidegrees, of the thermal zones.
+const thermalScript = `for z in /sys/class/thermal/thermal_zone*; do [ -r "$z/temp" ] && echo "$(cat "$z/type" 2>/dev/null) $(cat "$z/temp")"; done`
+
+// systemInfoScript collects everything in one round trip, each command printing its
+// section. The failing commands leave their section empty.
+const systemInfoScript = `s() { echo "` + sectionMarker + `$1"; }
+s buildinfo; cat /etc/buildinfo 2>/dev/null
+s kernel; uname -srm 2>/dev/null
+s uptime; cat /proc/uptime 2>/dev/null
+s loadavg; cat /proc/loadavg 2>/dev/null
+s cpus; nproc 2>/dev/null
+s meminfo; cat /proc/meminfo 2>/dev/null
+s df; df -P -k 2>/dev/null
+s thermal; ` + thermalScript + `
+s docker; docker version --format '{{.Server.Version}}' 2>/dev/null
+s orchestrator; dpkg-query -W -f='${Version}' arduino-app-cli 2>/dev/null
+s router; dpkg-query -W -f='${Version}' arduino-router 2>/dev/null
+s core; arduino-cli core list 2>/dev/null
+true
+`
+
+// imageVersionKeys are the /etc/buildinfo keys holding the image version, by priority.
+var imageVersionKeys = []string{"BUILD_ID", "IMAGE_VERSION", "VERSION", "VERSION_ID"}
+
+// pseudoFilesystems are not reported in the disk usage.
+var pseudoFilesystems = []string{"tmpfs", "devtmpfs", "overlay", "squashfs", "none", "udev", "efivarfs"}
+
+type SystemInfo struct {
+	Image         ImageInfo     `json:"image"`
+	Kernel        string        `json:"kernel"`
+	UptimeSeconds float64       `json:"uptimeSeconds"`
+	Load          [3]float64    `json:"load"`
+	CPUs          int           `json:"cpus"`
+	Memory        MemoryInfo    `json:"memory"`
+	Disks         []DiskUsage   `json:"disks"`
+	Temperatures  []Temperature `json:"temperatures"`
+	// DockerVersion and OrchestratorVersion are empty when not installed.
+	DockerVersion       string    `json:"dockerVersion"`
+	OrchestratorVersion string    `json:"orchestratorVersion"`
+	MCU                 MCUInfo   `json:"mcu"`
+	CollectedAt         time.Time `json:"collectedAt"`
+}
+
+// ImageInfo is the OS image of the board, read from /etc/buildinfo. R0 builds do not
+// have it, and their version is empty.
+type ImageInfo struct {
+	Version string            `json:"version"`
+	Fields  map[string]string `json:"fields"`
+}
+
+type MemoryInfo struct {
+	TotalBytes     uint64 `json:"totalBytes"`
+	AvailableBytes uint64 `json:"availableBytes"`
+	SwapTotalBytes uint64 `json:"swapTotalBytes"`
+	SwapFreeBytes  uint64 `json:"swapFreeBytes"`
+}
+
+type DiskUsage struct {
+	Filesystem     string `json:"filesystem"`
+	Mount          string `json:"mount"`
+	TotalBytes     uint64 `json:"totalBytes"`
+	UsedBytes      uint64 `json:"usedBytes"`
+	AvailableBytes uint64 `json:"availableBytes"`
+}
+
+type Temperature struct {
+	Zone    string  `json:"zone"`
+	Celsius float64 `json:"celsius"`
+}
+
+// MCUInfo describes the software driving the microcontroller: the router bridging
+// it to the Linux side and the Zephyr core the sketches are built with.
+type MCUInfo struct {
+	RouterVersion string `json:"routerVersion"`
+	CoreID        string `json:"coreId"`
+	CoreVersion   string `json:"coreVersion"`
+}
+
+// GetSystemInfo collects the image, kernel, resources and software versions of the board.
+func (b *Board) GetSystemInfo(ctx context.Context) (SystemInfo, error) {
+	res, err := b.RunCommand(ctx, "sh", []string{"-c", systemInfoScript})
+	if err != nil {
+		return SystemInfo{}, fmt.Errorf("failed to get system info: %w", err)
+	}
+	if res.ExitCode != 0 {
+		return SystemInfo{}, fmt.Errorf("failed to get system info: exit status %d: %s", res.ExitCode, strings.TrimSpace(res.Stderr))
+	}
+	info := parseSystemInfo(res.Stdout)
+	info.CollectedAt = time.Now()
+	return info, nil
+}
+
+func parseSystemInfo(out string) SystemInfo {
+	sections := splitSections(out)
+	info := SystemInfo{
+		Image:               parseBuildInfo(sections["buildinfo"]),
+		Kernel:              strings.TrimSpace(sections["kernel"]),
+		Memory:              parseMeminfo(sections["meminfo"]),
+		Disks:               parseDf(sections["df"]),
+		Temperatures:        parseThermal(sections["thermal"]),
+		DockerVersion:       strings.TrimSpace(sections["docker"]),
+		OrchestratorVersion: strings.TrimSpace(sections["orchestrator"]),
+		MCU:                 MCUInfo{RouterVersion: strings.TrimSpace(sections["router"])},
+	}
+	if f := strings.Fields(sections["uptime"]); len(f) > 0 {
+		info.UptimeSeconds, _ = strconv.ParseFloat(f[0], 64)
+	}
+	if f := strings.Fields(sections["loadavg"]); len(f) >= 3 {
+		for i := range info.Load {
+			info.Load[i], _ = strconv.ParseFloat(f[i], 64)
+		}
+	}
+	info.CPUs, _ = strconv.Atoi(strings.TrimSpace(sections["cpus"]))
+	info.MCU.CoreID, info.MCU.CoreVersion = parseZephyrCore(sections["core"])
+	return info
+}
+
+func splitSections(out string) map[string]string {
+	sections := make(map[string]string)
+	var name string
+	var content strings.Builder
+	flush := func() {
+		if name != "" {
+			sections[name] = content.String()
+		}
+		content.Reset()
+	}
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if after, ok := strings.CutPrefix(line, sectionMarker); ok {
+			flush()
+			name = after
+			continue
+		}
+		content.WriteString(line)
+		content.WriteByte('\n')
+	}
+	flush()
+	return sections
+}
+
+// parseBuildInfo parses the KEY=value lines of /etc/buildinfo. A file with a bare
+// line is taken as the version itself.
+func parseBuildInfo(s string) ImageInfo {
+	info := ImageInfo{Fields: map[string]string{}}
+	var bare string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			if bare == "" {
+				bare = line
+			}
+			continue
+		}
+		info.Fields[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	for _, k := range imageVersionKeys {
+		if v := info.Fields[k]; v != "" {
+			info.Version = v
+			return info
+		}
+	}
+	info.Version = bare
+	return info
+}
+
+func parseMeminfo(s string) MemoryInfo {
+	var m MemoryInfo
+	for _, line := range strings.Split(s, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		f := strings.Fields(value)
+		if len(f) == 0 {
+			continue
+		}
+		n, err := strconv.ParseUint(f[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		// The values are in kB, the unit is omitted for the page counts.
+		if len(f) > 1 && f[1] == "kB" {
+			n *= 1024
+		}
+		switch key {
+		case "MemTotal":
+			m.TotalBytes = n
+		case "MemAvailable":
+			m.AvailableBytes = n
+		case "SwapTotal":
+			m.SwapTotalBytes = n
+		case "SwapFree":
+			m.SwapFreeBytes = n
+		}
+	}
+	return m
+}
+
+// parseDf parses the POSIX output of df -k, skipping the pseudo file systems.
+func parseDf(s string) []DiskUsage {
+	disks := []DiskUsage{}
+	for i, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if i == 0 || len(f) < 6 {
+			continue
+		}
+		fs := f[0]
+		if slices.Contains(pseudoFilesystems, fs) {
+			continue
+		}
+		total, err1 := strconv.ParseUint(f[1], 10, 64)
+		used, err2 := strconv.ParseUint(f[2], 10, 64)
+		avail, err3 := strconv.ParseUint(f[3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || total == 0 {
+			continue
+		}
+		disks = append(disks, DiskUsage{
+			Filesystem:     fs,
+			Mount:          strings.Join(f[5:], " "),
+			TotalBytes:     total * 1024,
+			UsedBytes:      used * 1024,
+			AvailableBytes: avail * 1024,
+		})
+	}
+	return disks
+}
+
+// parseThermal parses the "<type> <millidegrees>" lines of the thermal zones.
+func parseThermal(s string) []Temperature {
+	temps := []Temperature{}
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		milli, err := strconv.ParseFloat(f[len(f)-1], 64)
+		if err != nil {
+			continue
+		}
+		zone := strings.Join(f[:len(f)-1], " ")
+		if zone == "" {
+			zone = fmt.Sprintf("zone%d", len(temps))
+		}
+		temps = append(temps, Temperature{Zone: zone, Celsius: milli / 1000})
+	}
+	return temps
+}
+
+// parseZephyrCore returns the id and version of the Zephyr core from the output of
+// arduino-cli core list.
+func parseZephyrCore(s string) (id string, version string) {
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if len(f) >= 2 && strings.Contains(f[0], "zephyr") {
+			return f[0], f[1]
+		}
+	}
+	return "", ""
+}
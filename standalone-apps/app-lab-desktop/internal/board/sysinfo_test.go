@@ -0,0 +1,88 @@
+package board
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSystemInfo(t *testing.T) {
+	out := `@@@ buildinfo
+BUILD_ID="20251123-445"
+VARIANT=debian
+@@@ kernel
+Linux 6.16.7-g0dd0d8ab3bb9 aarch64
+@@@ uptime
+3725.42 13902.71
+@@@ loadavg
+0.52 0.31 0.20 2/311 4242
+@@@ cpus
+4
+@@@ meminfo
+MemTotal:        1860892 kB
+MemFree:          212340 kB
+MemAvailable:    1204708 kB
+SwapTotal:             0 kB
+SwapFree:              0 kB
+HugePages_Total:       0
+@@@ df
+Filesystem     1024-blocks    Used Available Capacity Mounted on
+/dev/mmcblk0p68    9632124 6175140   2947952      68% /
+tmpfs               930444       0    930444       0% /dev/shm
+/dev/mmcblk0p1       65390   12294     53096      19% /boot/my efi
+@@@ thermal
+cpu0-thermal 45500
+gpu-thermal 41200
+broken
+@@@ docker
+28.5.2
+@@@ orchestrator
+0.6.4
+@@@ router
+@@@ core
+ID                  Installed Latest Name
+arduino:zephyr      0.51.0    0.51.0 Arduino Zephyr Boards
+`
+	got := parseSystemInfo(out)
+	expected := SystemInfo{
+		Image: ImageInfo{
+			Version: "20251123-445",
+			Fields:  map[string]string{"BUILD_ID": "20251123-445", "VARIANT": "debian"},
+		},
+		Kernel:        "Linux 6.16.7-g0dd0d8ab3bb9 aarch64",
+		UptimeSeconds: 3725.42,
+		Load:          [3]float64{0.52, 0.31, 0.20},
+		CPUs:          4,
+		Memory:        MemoryInfo{TotalBytes: 1860892 * 1024, AvailableBytes: 1204708 * 1024},
+		Disks: []DiskUsage{
+			{Filesystem: "/dev/mmcblk0p68", Mount: "/", TotalBytes: 9632124 * 1024, UsedBytes: 6175140 * 1024, AvailableBytes: 2947952 * 1024},
+			{Filesystem: "/dev/mmcblk0p1", Mount: "/boot/my efi", TotalBytes: 65390 * 1024, UsedBytes: 12294 * 1024, AvailableBytes: 53096 * 1024},
+		},
+		Temperatures:        []Temperature{{Zone: "cpu0-thermal", Celsius: 45.5}, {Zone: "gpu-thermal", Celsius: 41.2}},
+		DockerVersion:       "28.5.2",
+		OrchestratorVersion: "0.6.4",
+		MCU:                 MCUInfo{CoreID: "arduino:zephyr", CoreVersion: "0.51.0"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestParseBuildInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "missing file", content: "", expected: ""},
+		{name: "build id", content: "VERSION=1.2\nBUILD_ID=445\n", expected: "445"},
+		{name: "version only", content: "# image\nVERSION='1.2'\n", expected: "1.2"},
+		{name: "bare version", content: "20251123-445\n", expected: "20251123-445"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseBuildInfo(tt.content).Version; got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
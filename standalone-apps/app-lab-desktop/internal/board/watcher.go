@@ -0,0 +1,241 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/arduino/arduino-cli/commands"
+	rpc "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/commands/v1"
+)
+
+// Discovery events usually come in bursts (e.g. serial and network ports of the same
+// board), wait for them to settle before refreshing the list.
+const watcherRefreshDelay = 500 * time.Millisecond
+
+const (
+	watcherMinBackoff = 1 * time.Second
+	watcherMaxBackoff = time.Minute
+)
+
+type EventType string
+
+const (
+	BoardAdded   EventType = "added"
+	BoardRemoved EventType = "removed"
+	// BoardChanged is emitted when a board is still there but reachable through a
+	// different protocol, e.g. it has been unplugged from USB and shows up on the network.
+	BoardChanged EventType = "changed"
+)
+
+type Event struct {
+	Type  EventType `json:"type"`
+	Board *Board    `json:"board"`
+}
+
+// Watcher keeps the list of detected boards up to date by listening to the
+// arduino-cli pluggable discoveries, instead of polling GetBoards.
+type Watcher struct {
+	onEvent func(Event)
+
+	mu     sync.Mutex
+	boards []*Board
+	ready  bool
+}
+
+func NewWatcher(onEvent func(Event)) *Watcher {
+	return &Watcher{
+		onEvent: onEvent,
+	}
+}
+
+// Boards returns the last known list of boards, and false until the first scan is completed.
+func (w *Watcher) Boards() ([]*Board, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.boards), w.ready
+}
+
+// Watch runs the watcher until ctx is done, restarting it with an exponential backoff
+// whenever the discoveries stop.
+func (w *Watcher) Watch(ctx context.Context) {
+	backoff := watcherMinBackoff
+	for {
+		start := time.Now()
+		err := w.Run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		// A watcher that ran for a while is restarted quickly.
+		if time.Since(start) > watcherMaxBackoff {
+			backoff = watcherMinBackoff
+		}
+		slog.Error("board watcher stopped, restarting", "err", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watcherMaxBackoff)
+	}
+}
+
+// Run watches the discoveries until ctx is done or they stop. The tooling must be
+// already installed. Once it returns, Boards reports the list as not ready, so that
+// the callers do not rely on a list that is not updated anymore.
+func (w *Watcher) Run(ctx context.Context) error {
+	if !isToolingInstalled() {
+		return errors.New("detection tools not installed")
+	}
+	defer func() {
+		w.mu.Lock()
+		w.ready = false
+		w.mu.Unlock()
+	}()
+
+	srv := commands.NewArduinoCoreServer()
+	resp, err := srv.Create(ctx, &rpc.CreateRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to create arduino-cli instance: %w", err)
+	}
+	inst := resp.GetInstance()
+	defer func() {
+		_, _ = srv.Destroy(context.Background(), &rpc.DestroyRequest{Instance: inst})
+	}()
+
+	if err := srv.Init(
+		&rpc.InitRequest{Instance: inst},
+		commands.InitStreamResponseToCallbackFunction(ctx, func(r *rpc.InitResponse) error {
+			slog.Debug("Arduino init instance", slog.String("instance", r.String()))
+			return nil
+		}),
+	); err != nil {
+		return fmt.Errorf("failed to init arduino-cli instance: %w", err)
+	}
+
+	stream, events := commands.BoardListWatchProxyToChan(ctx)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- srv.BoardListWatch(&rpc.BoardListWatchRequest{Instance: inst}, stream)
+	}()
+
+	w.refresh(ctx)
+
+	var refresh <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watchErr:
+			if err != nil {
+				return fmt.Errorf("board discovery stopped: %w", err)
+			}
+			return errors.New("board discovery stopped")
+		case ev, ok := <-events:
+			if !ok {
+				return errors.New("board discovery stopped")
+			}
+			switch ev.GetEventType() {
+			case "error":
+				slog.Warn("board discovery error", "err", ev.GetError())
+				continue
+			case "add":
+				if !matchesArduinoQ(ev.GetPort()) {
+					continue
+				}
+			}
+			refresh = time.After(watcherRefreshDelay)
+		case <-refresh:
+			refresh = nil
+			w.refresh(ctx)
+		}
+	}
+}
+
+func matchesArduinoQ(port *rpc.DetectedPort) bool {
+	return slices.ContainsFunc(port.GetMatchingBoards(), func(b *rpc.BoardListItem) bool {
+		return b.GetFqbn() == arduinoQFqbn
+	})
+}
+
+// refresh resolves the boards with the same code path as GetBoards, so that ids
+// are consistent between the watcher and a one-shot listing.
+func (w *Watcher) refresh(ctx context.Context) {
+	boards, err := detectBoards(ctx)
+	if err != nil {
+		slog.Error("failed to refresh boards", "err", err)
+		return
+	}
+
+	w.mu.Lock()
+	boards = keepKnownBoards(w.boards, boards)
+	events := diffBoards(w.boards, boards)
+	w.boards = boards
+	w.ready = true
+	w.mu.Unlock()
+
+	for _, e := range events {
+		w.onEvent(e)
+	}
+}
+
+// keepKnownBoards returns next, reusing the instances of prev for the boards that did not change.
+func keepKnownBoards(prev, next []*Board) []*Board {
+	result := make([]*Board, len(next))
+	for i, b := range next {
+		result[i] = b
+		if j := slices.IndexFunc(prev, func(p *Board) bool { return p.Id == b.Id && p.Info == b.Info }); j != -1 {
+			result[i] = prev[j]
+		}
+	}
+	return result
+}
+
+func diffBoards(prev, next []*Board) []Event {
+	has := func(list []*Board, id string) bool {
+		return slices.ContainsFunc(list, func(b *Board) bool { return b.Id == id })
+	}
+
+	var removed, added []*Board
+	for _, b := range prev {
+		if !has(next, b.Id) {
+			removed = append(removed, b)
+		}
+	}
+	for _, b := range next {
+		if !has(prev, b.Id) {
+			added = append(added, b)
+		}
+	}
+
+	var events []Event
+	for _, b := range next {
+		// Ids are stable, a board whose info changed (e.g. renamed) keeps its id.
+		if i := slices.IndexFunc(prev, func(p *Board) bool { return p.Id == b.Id }); i != -1 && prev[i].Info != b.Info {
+			events = append(events, Event{Type: BoardChanged, Board: b})
+		}
+	}
+	for _, b := range added {
+		i := slices.IndexFunc(removed, func(r *Board) bool {
+			return r.Info.Protocol != b.Info.Protocol && sameBoard(r, b)
+		})
+		if i == -1 {
+			events = append(events, Event{Type: BoardAdded, Board: b})
+			continue
+		}
+		removed = slices.Delete(removed, i, i+1)
+		events = append(events, Event{Type: BoardChanged, Board: b})
+	}
+	for _, b := range removed {
+		events = append(events, Event{Type: BoardRemoved, Board: b})
+	}
+	return events
+}
+
+func sameBoard(a, b *Board) bool {
+	return a.Info.Serial != "" && a.Info.Serial == b.Info.Serial
+}
@@ -0,0 +1,71 @@
+package board
+
+import (
+	"testing"
+)
+
+func TestDiffBoards(t *testing.T) {
+	usb := &Board{Id: "usb", Info: BoardInfo{Protocol: "serial", Serial: "SN1"}}
+	net := &Board{Id: "net", Info: BoardInfo{Protocol: "network", Serial: "SN1", Address: "192.168.1.10"}}
+	other := &Board{Id: "other", Info: BoardInfo{Protocol: "serial", Serial: "SN2"}}
+	renamed := &Board{Id: "usb", Info: BoardInfo{Protocol: "serial", Serial: "SN1", CustomName: "camera"}}
+
+	tests := []struct {
+		name     string
+		prev     []*Board
+		next     []*Board
+		expected []Event
+	}{
+		{
+			name:     "no changes",
+			prev:     []*Board{usb, other},
+			next:     []*Board{usb, other},
+			expected: nil,
+		},
+		{
+			name:     "board plugged in",
+			prev:     []*Board{usb},
+			next:     []*Board{usb, other},
+			expected: []Event{{Type: BoardAdded, Board: other}},
+		},
+		{
+			name:     "board unplugged",
+			prev:     []*Board{usb, other},
+			next:     []*Board{other},
+			expected: []Event{{Type: BoardRemoved, Board: usb}},
+		},
+		{
+			name:     "board moved from usb to network",
+			prev:     []*Board{usb, other},
+			next:     []*Board{net, other},
+			expected: []Event{{Type: BoardChanged, Board: net}},
+		},
+		{
+			name:     "board reachable on both protocols",
+			prev:     []*Board{usb},
+			next:     []*Board{usb, net},
+			expected: []Event{{Type: BoardAdded, Board: net}},
+		},
+		{
+			name:     "board renamed",
+			prev:     []*Board{usb, other},
+			next:     []*Board{renamed, other},
+			expected: []Event{{Type: BoardChanged, Board: renamed}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffBoards(tt.prev, tt.next)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %d events, got %d: %+v", len(tt.expected), len(got), got)
+			}
+			for i := range got {
+				if got[i].Type != tt.expected[i].Type || got[i].Board != tt.expected[i].Board {
+					t.Errorf("event %d mismatch\nGot: %s %s\nExpected: %s %s",
+						i, got[i].Type, got[i].Board.Id, tt.expected[i].Type, tt.expected[i].Board.Id)
+				}
+			}
+		})
+	}
+}
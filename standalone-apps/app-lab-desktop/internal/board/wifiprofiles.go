@@ -0,0 +1,217 @@
+package board
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	wifiConnectionType = "802-11-wireless"
+	// wifiConnectionsDir holds the NetworkManager keyfiles of the saved connections.
+	wifiConnectionsDir = "/etc/NetworkManager/system-connections"
+)
+
+// WiFiProfile is a Wi-Fi connection saved by NetworkManager on the board.
+type WiFiProfile struct {
+	Name        string `json:"name"`
+	SSID        string `json:"ssid"`
+	Hidden      bool   `json:"hidden"`
+	AutoConnect bool   `json:"autoConnect"`
+	// KeyMgmt is the security of the network, e.g. "wpa-psk", empty for open networks.
+	KeyMgmt string `json:"keyMgmt,omitempty"`
+	PSK     string `json:"psk,omitempty"`
+}
+
+// wifiProfileFields are read with nmcli -g, the secret last since its line is missing
+// when empty.
+var wifiProfileFields = []string{
+	"802-11-wireless.ssid",
+	"802-11-wireless.hidden",
+	"connection.autoconnect",
+	"802-11-wireless-security.key-mgmt",
+	"802-11-wireless-security.psk",
+}
+
+// ListWiFiProfiles returns the Wi-Fi connections saved on the board, with their
+// secrets when the board user is allowed to read them.
+func (b *Board) ListWiFiProfiles(ctx context.Context) ([]WiFiProfile, error) {
+	out, err := b.output(ctx, "nmcli", "-t", "-f", "NAME,TYPE", "connection", "show")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Wi-Fi profiles: %w", err)
+	}
+
+	profiles := []WiFiProfile{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := splitTerse(line)
+		if len(fields) != 2 || fields[1] != wifiConnectionType {
+			continue
+		}
+		p, err := b.readWiFiProfile(ctx, fields[0])
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func (b *Board) readWiFiProfile(ctx context.Context, name string) (WiFiProfile, error) {
+	args := []string{"-s", "-g", strings.Join(wifiProfileFields, ","), "connection", "show", name}
+	out, err := b.output(ctx, "nmcli", args...)
+	if err != nil {
+		// Open networks have no security setting.
+		args[2] = strings.Join(wifiProfileFields[:3], ",")
+		if out, err = b.output(ctx, "nmcli", args...); err != nil {
+			return WiFiProfile{}, fmt.Errorf("failed to read Wi-Fi profile %s: %w", name, err)
+		}
+	}
+	p := parseWiFiProfile(name, out)
+	if p.KeyMgmt != "" && p.PSK == "" {
+		// The secrets are hidden to the users that are not allowed to modify the
+		// connection, root is, if sudo requires no password.
+		if out, err := b.output(ctx, "sudo", append([]string{"-n", "nmcli"}, args...)...); err == nil {
+			p = parseWiFiProfile(name, out)
+		}
+	}
+	return p, nil
+}
+
+// AddWiFiProfile saves a Wi-Fi connection on the board, without connecting to it.
+func (b *Board) AddWiFiProfile(ctx context.Context, p WiFiProfile) error {
+	if p.Name == "" || p.SSID == "" {
+		return fmt.Errorf("invalid Wi-Fi profile: name and SSID are required")
+	}
+	if p.KeyMgmt != "" {
+		if p.PSK == "" {
+			return fmt.Errorf("invalid Wi-Fi profile %s: missing secret for %s", p.Name, p.KeyMgmt)
+		}
+		return b.addWiFiKeyfile(ctx, p)
+	}
+	args := []string{"connection", "add", "type", "wifi", "con-name", p.Name, "ifname", "*", "ssid", p.SSID,
+		"802-11-wireless.hidden", yesNo(p.Hidden), "connection.autoconnect", yesNo(p.AutoConnect)}
+	if _, err := b.runPrivileged(ctx, "nmcli", args...); err != nil {
+		return fmt.Errorf("failed to add Wi-Fi profile %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// addWiFiKeyfile saves a Wi-Fi connection with a secret as a NetworkManager keyfile,
+// since on the nmcli command line the secret would show in the process list. The
+// keyfile is written in a temporary directory private to the board user, then
+// installed readable by root only.
+func (b *Board) addWiFiKeyfile(ctx context.Context, p WiFiProfile) error {
+	uuid, err := newUUID()
+	if err != nil {
+		return err
+	}
+	out, err := b.output(ctx, "mktemp", "-d")
+	if err != nil {
+		return fmt.Errorf("failed to add Wi-Fi profile %s: %w", p.Name, err)
+	}
+	tmpDir := strings.TrimSpace(out)
+	defer func() {
+		_, _ = b.output(context.WithoutCancel(ctx), "rm", "-rf", tmpDir)
+	}()
+
+	src := path.Join(tmpDir, uuid+".nmconnection")
+	if err := b.Conn().WriteFile(strings.NewReader(wifiKeyfile(p, uuid)), src); err != nil {
+		return fmt.Errorf("failed to add Wi-Fi profile %s: %w", p.Name, err)
+	}
+	dest := path.Join(wifiConnectionsDir, uuid+".nmconnection")
+	if _, err := b.runPrivileged(ctx, "install", "-m", "600", "-o", "root", "-g", "root", src, dest); err != nil {
+		return fmt.Errorf("failed to add Wi-Fi profile %s: %w", p.Name, err)
+	}
+	if _, err := b.runPrivileged(ctx, "nmcli", "connection", "load", dest); err != nil {
+		_, _ = b.runPrivileged(context.WithoutCancel(ctx), "rm", "-f", dest)
+		return fmt.Errorf("failed to add Wi-Fi profile %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// wifiKeyfile returns the NetworkManager keyfile of the connection p. The SSID is
+// written as a list of bytes, that needs no escaping.
+func wifiKeyfile(p WiFiProfile, uuid string) string {
+	var ssid strings.Builder
+	for _, c := range []byte(p.SSID) {
+		ssid.WriteString(strconv.Itoa(int(c)) + ";")
+	}
+	var f strings.Builder
+	fmt.Fprintf(&f, "[connection]\nid=%s\nuuid=%s\ntype=wifi\nautoconnect=%t\n\n", escapeKeyfile(p.Name), uuid, p.AutoConnect)
+	fmt.Fprintf(&f, "[wifi]\nmode=infrastructure\nssid=%s\nhidden=%t\n\n", ssid.String(), p.Hidden)
+	fmt.Fprintf(&f, "[wifi-security]\nkey-mgmt=%s\npsk=%s\n\n", escapeKeyfile(p.KeyMgmt), escapeKeyfile(p.PSK))
+	f.WriteString("[ipv4]\nmethod=auto\n\n[ipv6]\nmethod=auto\n")
+	return f.String()
+}
+
+// escapeKeyfile escapes a string value of a keyfile, as GLib reads them.
+func escapeKeyfile(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s)
+	if strings.HasPrefix(s, " ") {
+		s = `\s` + s[1:]
+	}
+	return s
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", fmt.Errorf("failed to generate UUID: %w", err)
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
+
+func parseWiFiProfile(name string, out string) WiFiProfile {
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	for len(lines) < len(wifiProfileFields) {
+		lines = append(lines, "")
+	}
+	value := func(i int) string {
+		return unescapeTerse(lines[i])
+	}
+	return WiFiProfile{
+		Name:        name,
+		SSID:        value(0),
+		Hidden:      value(1) == "yes",
+		AutoConnect: value(2) != "no",
+		KeyMgmt:     value(3),
+		PSK:         value(4),
+	}
+}
+
+// splitTerse splits a line of the nmcli terse output on the unescaped colons.
+func splitTerse(line string) []string {
+	var fields []string
+	var field strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case line[i] == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(line[i])
+		}
+	}
+	return append(fields, field.String())
+}
+
+func unescapeTerse(s string) string {
+	fields := splitTerse(s)
+	return strings.Join(fields, ":")
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,56 @@
+package board
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWiFiProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected WiFiProfile
+	}{
+		{
+			name:     "wpa",
+			output:   "Home\\: 2.4GHz\nno\nyes\nwpa-psk\nse\\:cret\\\\\n",
+			expected: WiFiProfile{Name: "home", SSID: "Home: 2.4GHz", AutoConnect: true, KeyMgmt: "wpa-psk", PSK: `se:cret\`},
+		},
+		{
+			name:     "secret not readable",
+			output:   "Office\nyes\nno\nwpa-psk\n",
+			expected: WiFiProfile{Name: "home", SSID: "Office", Hidden: true, KeyMgmt: "wpa-psk"},
+		},
+		{
+			name:     "open network",
+			output:   "Cafe\nno\nyes\n",
+			expected: WiFiProfile{Name: "home", SSID: "Cafe", AutoConnect: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseWiFiProfile("home", tt.output); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSplitTerse(t *testing.T) {
+	got := splitTerse(`My\:Net:802-11-wireless`)
+	expected := []string{"My:Net", "802-11-wireless"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestWiFiKeyfile(t *testing.T) {
+	p := WiFiProfile{Name: " home", SSID: "Ho;me", Hidden: true, KeyMgmt: "wpa-psk", PSK: `se\cret`}
+	expected := "[connection]\nid=\\shome\nuuid=1\ntype=wifi\nautoconnect=false\n\n" +
+		"[wifi]\nmode=infrastructure\nssid=72;111;59;109;101;\nhidden=true\n\n" +
+		"[wifi-security]\nkey-mgmt=wpa-psk\npsk=se\\\\cret\n\n" +
+		"[ipv4]\nmethod=auto\n\n[ipv6]\nmethod=auto\n"
+	if got := wifiKeyfile(p, "1"); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
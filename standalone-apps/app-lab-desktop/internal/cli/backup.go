@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"app-lab-desktop/internal/backup"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newBackupCommand(version string, flags *globalFlags) *cobra.Command {
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Board backup of the apps, settings, Wi-Fi profiles and SSH files",
+	}
+
+	var output string
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Write a backup of the board to a zip, holding its secrets",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			if output == "" {
+				output = backup.FileName(b)
+			}
+			fmt.Fprintln(os.Stderr, "Backing up the board...")
+			manifest, err := backup.Create(cmd.Context(), b, version, output)
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]any{"path": output, "manifest": manifest})
+		},
+	}
+	createCmd.Flags().StringVarP(&output, "output", "o", "", "path of the zip (defaults to a timestamped name in the current directory)")
+	backupCmd.AddCommand(createCmd)
+
+	var dryRun, overwriteSSH bool
+	restoreCmd := &cobra.Command{
+		Use:   "restore <backup.zip>",
+		Short: "Restore a backup onto the board",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			plan, err := backup.Restore(cmd.Context(), b, args[0], dryRun, overwriteSSH)
+			if plan.Actions != nil {
+				if printErr := printJSON(plan); printErr != nil {
+					return printErr
+				}
+			}
+			return err
+		},
+	}
+	restoreCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what would be restored without modifying the board")
+	restoreCmd.Flags().BoolVar(&overwriteSSH, "overwrite-ssh", false, "replace the SSH files already on the board, e.g. its private keys and config")
+	backupCmd.AddCommand(restoreCmd)
+
+	return backupCmd
+}
@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"app-lab-desktop/internal/board"
+	"app-lab-desktop/internal/network"
+	"app-lab-desktop/internal/network/ethernet"
+	"app-lab-desktop/internal/network/wifi"
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+type boardInfo struct {
+	Board            *board.Board       `json:"board"`
+	Name             string             `json:"name"`
+	KeyboardLayout   string             `json:"keyboardLayout"`
+	NeedsImageUpdate bool               `json:"needsImageUpdate"`
+	WiFi             wifi.WifiStatus    `json:"wifi"`
+	Ethernet         ethernet.EthStatus `json:"ethernet"`
+	Internet         bool               `json:"internet"`
+	ConnectionName   *string            `json:"connectionName"`
+}
+
+func newBoardsCommand(flags *globalFlags) *cobra.Command {
+	boardsCmd := &cobra.Command{
+		Use:   "boards",
+		Short: "Detected boards",
+	}
+	boardsCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the detected boards",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			boards, err := detectBoards(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(boards)
+		},
+	})
+	boardsCmd.AddCommand(&cobra.Command{
+		Use:   "known",
+		Short: "List every board seen so far, including the unreachable ones",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			known, err := board.KnownBoards()
+			if err != nil {
+				return err
+			}
+			return printJSON(known)
+		},
+	})
+	boardsCmd.AddCommand(&cobra.Command{
+		Use:   "nickname <identity> [nickname]",
+		Short: "Set the local nickname of a known board, or remove it",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nickname := ""
+			if len(args) == 2 {
+				nickname = args[1]
+			}
+			return board.SetNickname(args[0], nickname)
+		},
+	})
+	boardsCmd.AddCommand(&cobra.Command{
+		Use:   "prefer <identity> [serial|network]",
+		Short: "Set the protocol to connect with to a board reachable over both USB and network, or remove it",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			protocol := ""
+			if len(args) == 2 {
+				protocol = args[1]
+			}
+			return board.SetPreferredProtocol(args[0], protocol)
+		},
+	})
+	boardsCmd.AddCommand(newBoardsAddCommand(flags))
+	boardsCmd.AddCommand(&cobra.Command{
+		Use:   "remove <id|address>",
+		Short: "Remove a manually added board",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return board.RemoveManualBoard(args[0])
+		},
+	})
+	return boardsCmd
+}
+
+func newBoardsAddCommand(flags *globalFlags) *cobra.Command {
+	var (
+		port int
+		name string
+	)
+	addCmd := &cobra.Command{
+		Use:   "add <address>",
+		Short: "Add a network board by IP address or hostname",
+		Long:  "Add a network board that the discovery cannot find. Without --name, the name is read from the board, which requires --password.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := board.AddManualBoard(cmd.Context(), args[0], port, name, flags.boardPassword())
+			if err != nil {
+				return err
+			}
+			return printJSON(b)
+		},
+	}
+	addCmd.Flags().IntVar(&port, "port", 22, "SSH port of the board")
+	addCmd.Flags().StringVar(&name, "name", "", "name of the board")
+	return addCmd
+}
+
+func newBoardCommand(flags *globalFlags) *cobra.Command {
+	boardCmd := &cobra.Command{
+		Use:   "board",
+		Short: "Selected board",
+	}
+	boardCmd.AddCommand(&cobra.Command{
+		Use:   "info",
+		Short: "Show name, image and network status of the board",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			b, closeBoard, err := connectBoard(ctx, flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			info := boardInfo{
+				Board:            b,
+				NeedsImageUpdate: b.IsR0Build(),
+			}
+			if info.Name, err = b.GetName(ctx); err != nil {
+				return fmt.Errorf("failed to get board name: %w", err)
+			}
+			if info.KeyboardLayout, err = b.GetKeyboardLayout(ctx); err != nil {
+				return fmt.Errorf("failed to get keyboard layout: %w", err)
+			}
+			if info.WiFi, err = wifi.GetWiFiStatus(ctx, b.Conn()); err != nil {
+				return err
+			}
+			if info.Ethernet, err = ethernet.GetEthStatus(ctx, b.Conn()); err != nil {
+				return err
+			}
+			if info.Internet, err = network.GetInternetStatus(ctx, b.Conn()); err != nil {
+				return err
+			}
+			if info.ConnectionName, err = network.GetConnectionName(ctx, b.Conn()); err != nil {
+				return err
+			}
+			return printJSON(info)
+		},
+	})
+	boardCmd.AddCommand(&cobra.Command{
+		Use:   "system",
+		Short: "Show image version, kernel, resources and software versions of the board",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			info, err := b.GetSystemInfo(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(info)
+		},
+	})
+	boardCmd.AddCommand(newBoardKeyCommand(flags))
+	boardCmd.AddCommand(newBoardTimezoneCommand(flags))
+	boardCmd.AddCommand(newBoardLocaleCommand(flags))
+	boardCmd.AddCommand(newBoardNTPCommand(flags))
+	return boardCmd
+}
+
+func newBoardKeyCommand(flags *globalFlags) *cobra.Command {
+	keyCmd := &cobra.Command{
+		Use:   "key",
+		Short: "App SSH key, that lets network boards connect without password",
+	}
+	keyCmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Print the app public key, generating it if needed",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := board.AppPublicKey()
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"publicKey": key})
+		},
+	})
+	keyCmd.AddCommand(&cobra.Command{
+		Use:   "install",
+		Short: "Authorize the app key on the board, over USB or with --password",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+			return b.InstallKey(cmd.Context())
+		},
+	})
+	keyCmd.AddCommand(&cobra.Command{
+		Use:   "remove",
+		Short: "Remove the app key from the board",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+			return b.RemoveKey(cmd.Context())
+		},
+	})
+	return keyCmd
+}
+
+func detectBoards(ctx context.Context) ([]*board.Board, error) {
+	if err := board.InstallToolingIfMissing(ctx); err != nil {
+		return nil, fmt.Errorf("failed to install detection tools: %w", err)
+	}
+	return board.GetBoards(ctx)
+}
+
+func findBoard(boards []*board.Board, query string) (*board.Board, error) {
+	if query == "" {
+		if len(boards) == 0 {
+			return nil, fmt.Errorf("no board found")
+		}
+		// The same board reachable over both USB and network is connected with its
+		// preferred protocol.
+		if b := board.Preferred(boards); b != nil {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%d boards found, select one with --board", len(boards))
+	}
+	var found []*board.Board
+	for _, b := range boards {
+		if b.Id == query || b.Info.Serial == query || b.Info.Address == query ||
+			(b.Known != nil && b.Known.Nickname == query) {
+			found = append(found, b)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("board %s not found", query)
+	}
+	if b := board.Preferred(found); b != nil {
+		return b, nil
+	}
+	return found[0], nil
+}
+
+// connectBoard establishes a connection to the board selected by the global flags.
+// The returned func must be called once done: adb forwards would outlive the process otherwise.
+func connectBoard(ctx context.Context, flags *globalFlags) (*board.Board, func(), error) {
+	var b *board.Board
+	if board.IsSBC() {
+		var err error
+		if b, err = board.GetSbcBoard(ctx); err != nil {
+			return nil, nil, err
+		}
+		return b, func() { b.Close(ctx) }, nil
+	}
+
+	boards, err := detectBoards(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b, err = findBoard(boards, flags.board); err != nil {
+		return nil, nil, err
+	}
+	if err := b.EstablishConnection(ctx, flags.boardPassword()); err != nil {
+		board.InvalidateCredentials(err)
+		return nil, nil, err
+	}
+	return b, func() { b.Close(ctx) }, nil
+}
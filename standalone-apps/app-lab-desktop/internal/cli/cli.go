@@ -0,0 +1,103 @@
+package cli
+
+// Headless command mode: every command runs once against a board and prints its
+// result as JSON, going through the same board, fs and network code paths as the GUI.
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+const passwordEnv = "ARDUINO_APP_LAB_BOARD_PASSWORD"
+
+type globalFlags struct {
+	board    string
+	password string
+}
+
+// boardPassword returns the --password flag or, if not set, the password of the
+// environment, that is not the flag default so that the usage never shows it.
+func (f *globalFlags) boardPassword() string {
+	if f.password != "" {
+		return f.password
+	}
+	return os.Getenv(passwordEnv)
+}
+
+func newRootCommand(version string) *cobra.Command {
+	flags := &globalFlags{}
+
+	root := &cobra.Command{
+		Use:           filepath.Base(os.Args[0]),
+		Short:         "Arduino App Lab",
+		Long:          "Run without arguments to open the Arduino App Lab window, or use one of the commands below to work headless.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.CompletionOptions.DisableDefaultCmd = true
+	root.PersistentFlags().StringVarP(&flags.board, "board", "b", "", "board id, serial number, address or nickname (defaults to the only detected board)")
+	root.PersistentFlags().StringVarP(&flags.password, "password", "p", "", "board password, required by network boards without the app key (env "+passwordEnv+")")
+
+	root.AddCommand(
+		newVersionCommand(version),
+		newBoardsCommand(flags),
+		newBoardCommand(flags),
+		newFSCommand(flags),
+		newWiFiCommand(flags),
+		newCredentialsCommand(),
+		newHostKeysCommand(),
+		newForwardCommand(flags),
+		newRunCommand(flags),
+		newDiagnosticsCommand(version, flags),
+		newBackupCommand(version, flags),
+	)
+	return root
+}
+
+// IsCommand reports whether arg selects the headless mode instead of the GUI.
+func IsCommand(arg string) bool {
+	if slices.Contains([]string{"help", "--help", "-h"}, arg) {
+		return true
+	}
+	for _, c := range newRootCommand("").Commands() {
+		if c.Name() == arg || slices.Contains(c.Aliases, arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the command line and returns the process exit code.
+func Run(version string, args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	root := newRootCommand(version)
+	root.SetArgs(args)
+	if err := root.ExecuteContext(ctx); err != nil {
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			return exitErr.code
+		}
+		printError(err)
+		return 1
+	}
+	return 0
+}
+
+func newVersionCommand(version string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Show the application version",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printJSON(map[string]string{"version": version})
+		},
+	}
+}
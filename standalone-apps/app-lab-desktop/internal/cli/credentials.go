@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"app-lab-desktop/internal/board"
+
+	"github.com/spf13/cobra"
+)
+
+func newCredentialsCommand() *cobra.Command {
+	credentialsCmd := &cobra.Command{
+		Use:   "credentials",
+		Short: "Board passwords stored in the encrypted vault",
+		Long:  "Passwords that work are stored in the vault and used when --password is omitted. A vault protected by a passphrase is unlocked with the ARDUINO_APP_LAB_VAULT_PASSPHRASE environment variable.",
+	}
+
+	credentialsCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the boards with a stored password",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := board.Credentials()
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]any{"status": v.Status(), "entries": v.List()})
+		},
+	})
+
+	credentialsCmd.AddCommand(&cobra.Command{
+		Use:   "forget <identity>",
+		Short: "Remove the stored password of a board",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return board.ForgetPassword(args[0])
+		},
+	})
+
+	var passphrase string
+	rotateCmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Re-encrypt the vault with a new key, derived from --passphrase or random",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := board.Credentials()
+			if err != nil {
+				return err
+			}
+			return v.Rotate(passphrase)
+		},
+	}
+	rotateCmd.Flags().StringVar(&passphrase, "passphrase", "", "new passphrase, a random key file is used if empty")
+	credentialsCmd.AddCommand(rotateCmd)
+
+	return credentialsCmd
+}
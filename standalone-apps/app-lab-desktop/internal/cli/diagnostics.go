@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"app-lab-desktop/internal/board"
+	"app-lab-desktop/internal/diagnostics"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newDiagnosticsCommand(version string, flags *globalFlags) *cobra.Command {
+	var output string
+	var appOnly bool
+	cmd := &cobra.Command{
+		Use:   "diagnostics",
+		Short: "Write a zip with the logs and the state of the board and of the app, with the secrets redacted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var b *board.Board
+			if !appOnly {
+				var closeBoard func()
+				var err error
+				b, closeBoard, err = connectBoard(cmd.Context(), flags)
+				if err != nil {
+					return err
+				}
+				defer closeBoard()
+			}
+
+			if output == "" {
+				output = diagnostics.FileName(b)
+			}
+			fmt.Fprintln(os.Stderr, "Collecting diagnostics...")
+			if err := diagnostics.Collect(cmd.Context(), b, version, output); err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"path": output})
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "path of the zip (defaults to a timestamped name in the current directory)")
+	cmd.Flags().BoolVar(&appOnly, "app-only", false, "collect only the app logs, without connecting to a board")
+	return cmd
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"app-lab-desktop/internal/board"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func newForwardCommand(flags *globalFlags) *cobra.Command {
+	forwardCmd := &cobra.Command{
+		Use:   "forward",
+		Short: "Named port forwards to services of the board",
+		Long:  "Named port forwards are stored per board and opened automatically when the board is connected in the app, or with \"forward open\".",
+	}
+
+	forwardCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the forwards of the board",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := selectBoard(cmd, flags)
+			if err != nil {
+				return err
+			}
+			forwards, err := b.ListForwards()
+			if err != nil {
+				return err
+			}
+			return printJSON(forwards)
+		},
+	})
+
+	var localPort int
+	addCmd := &cobra.Command{
+		Use:   "add <name> <board-port>",
+		Short: "Add a forward to a port of the board",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			boardPort, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid board port %q", args[1])
+			}
+			b, err := selectBoard(cmd, flags)
+			if err != nil {
+				return err
+			}
+			status, err := b.AddForward(cmd.Context(), board.Forward{Name: args[0], BoardPort: boardPort, LocalPort: localPort})
+			if err != nil {
+				return err
+			}
+			return printJSON(status)
+		},
+	}
+	addCmd.Flags().IntVar(&localPort, "local-port", 0, "port on this computer (defaults to the board port, or an available one)")
+	forwardCmd.AddCommand(addCmd)
+
+	forwardCmd.AddCommand(&cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a forward",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := selectBoard(cmd, flags)
+			if err != nil {
+				return err
+			}
+			return b.RemoveForward(cmd.Context(), args[0])
+		},
+	})
+
+	forwardCmd.AddCommand(&cobra.Command{
+		Use:   "open",
+		Short: "Connect the board and keep its forwards open until interrupted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			b.RestoreForwards(cmd.Context())
+			forwards, err := b.ListForwards()
+			if err != nil {
+				return err
+			}
+			if err := printJSON(forwards); err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, "Forwarding, press Ctrl+C to stop.")
+			<-cmd.Context().Done()
+			return nil
+		},
+	})
+
+	return forwardCmd
+}
+
+// selectBoard returns the board selected by the global flags, without connecting to it.
+func selectBoard(cmd *cobra.Command, flags *globalFlags) (*board.Board, error) {
+	boards, err := detectBoards(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+	return findBoard(boards, flags.board)
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"app-lab-desktop/internal/fs"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+)
+
+func newFSCommand(flags *globalFlags) *cobra.Command {
+	fsCmd := &cobra.Command{
+		Use:   "fs",
+		Short: "Board file system",
+	}
+
+	fsCmd.AddCommand(&cobra.Command{
+		Use:   "ls <path>",
+		Short: "List a directory on the board",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			nodes, err := fs.ListDir(args[0], b.Conn())
+			if err != nil {
+				return fmt.Errorf("failed to list %s: %w", args[0], err)
+			}
+			return printJSON(nodes)
+		},
+	})
+
+	fsCmd.AddCommand(&cobra.Command{
+		Use:   "cat <path>",
+		Short: "Print a file of the board to stdout",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			f, err := b.Conn().ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", args[0], err)
+			}
+			defer f.Close()
+			_, err = io.Copy(os.Stdout, f)
+			return err
+		},
+	})
+
+	fsCmd.AddCommand(&cobra.Command{
+		Use:   "put <local-path> <board-path>",
+		Short: "Copy a local file to the board",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, dst := args[0], args[1]
+			f, err := os.Open(src)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			if err := b.Conn().WriteFile(f, dst); err != nil {
+				return fmt.Errorf("failed to write %s: %w", dst, err)
+			}
+			return printJSON(map[string]string{"source": src, "destination": dst})
+		},
+	})
+
+	fsCmd.AddCommand(&cobra.Command{
+		Use:   "get <board-path> [local-path]",
+		Short: "Copy a file of the board to the local machine",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, dst := args[0], path.Base(args[0])
+			if len(args) == 2 {
+				dst = args[1]
+			}
+
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			r, err := b.Conn().ReadFile(src)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", src, err)
+			}
+			defer r.Close()
+
+			f, err := os.Create(dst)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(f, r); err != nil {
+				return fmt.Errorf("failed to write %s: %w", dst, err)
+			}
+			return printJSON(map[string]string{"source": src, "destination": dst})
+		},
+	})
+
+	return fsCmd
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"app-lab-desktop/internal/board"
+
+	"github.com/spf13/cobra"
+)
+
+func newHostKeysCommand() *cobra.Command {
+	hostKeysCmd := &cobra.Command{
+		Use:   "hostkeys",
+		Short: "SSH host keys pinned on the first connection to each network board",
+	}
+
+	hostKeysCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the pinned host keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pinned, err := board.PinnedHostKeys()
+			if err != nil {
+				return err
+			}
+			return printJSON(pinned)
+		},
+	})
+
+	hostKeysCmd.AddCommand(&cobra.Command{
+		Use:   "reset <identity>",
+		Short: "Forget the host key of a board, e.g. after re-flashing it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return board.ResetHostKey(args[0])
+		},
+	})
+
+	return hostKeysCmd
+}
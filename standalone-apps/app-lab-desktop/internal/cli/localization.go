@@ -0,0 +1,173 @@
+package cli
+
+import "github.com/spf13/cobra"
+
+func newBoardTimezoneCommand(flags *globalFlags) *cobra.Command {
+	timezoneCmd := &cobra.Command{
+		Use:   "timezone",
+		Short: "Timezone of the board",
+	}
+	timezoneCmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Print the timezone of the board",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			timezone, err := b.GetTimezone(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"timezone": timezone})
+		},
+	})
+	timezoneCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the timezones known by the board",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			timezones, err := b.ListTimezones(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(timezones)
+		},
+	})
+	timezoneCmd.AddCommand(&cobra.Command{
+		Use:   "set <timezone>",
+		Short: "Set the timezone of the board, e.g. Europe/Rome",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			return b.SetTimezone(cmd.Context(), args[0])
+		},
+	})
+	return timezoneCmd
+}
+
+func newBoardLocaleCommand(flags *globalFlags) *cobra.Command {
+	localeCmd := &cobra.Command{
+		Use:   "locale",
+		Short: "System locale of the board",
+	}
+	localeCmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Print the system locale of the board",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			locale, err := b.GetLocale(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"locale": locale})
+		},
+	})
+	localeCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the locales generated on the board",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			locales, err := b.ListLocales(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(locales)
+		},
+	})
+	localeCmd.AddCommand(&cobra.Command{
+		Use:   "set <locale>",
+		Short: "Set the system locale of the board, e.g. en_US.UTF-8",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			return b.SetLocale(cmd.Context(), args[0])
+		},
+	})
+	return localeCmd
+}
+
+func newBoardNTPCommand(flags *globalFlags) *cobra.Command {
+	ntpCmd := &cobra.Command{
+		Use:   "ntp",
+		Short: "Clock synchronization of the board with NTP",
+	}
+	ntpCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Print whether the board clock is synchronized",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			status, err := b.GetTimeSyncStatus(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(status)
+		},
+	})
+	ntpCmd.AddCommand(&cobra.Command{
+		Use:   "enable",
+		Short: "Synchronize the board clock with NTP",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			return b.SetTimeSync(cmd.Context(), true)
+		},
+	})
+	ntpCmd.AddCommand(&cobra.Command{
+		Use:   "disable",
+		Short: "Stop synchronizing the board clock with NTP",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			return b.SetTimeSync(cmd.Context(), false)
+		},
+	})
+	return ntpCmd
+}
@@ -0,0 +1,16 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+)
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printError(err error) {
+	_ = json.NewEncoder(os.Stderr).Encode(map[string]string{"error": err.Error()})
+}
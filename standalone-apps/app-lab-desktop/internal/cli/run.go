@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"app-lab-desktop/internal/board"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// exitCodeError makes the process exit with the code of a remote command, which has
+// already printed its own errors.
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func newRunCommand(flags *globalFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run <command> [args...]",
+		Short: "Run a command on the board, streaming its output and exiting with its exit code",
+		Example: "  run df -h\n" +
+			"  run -- docker logs -f --tail 100 my-app",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			code, err := b.StreamCommand(cmd.Context(), args[0], args[1:], func(stream board.OutputStream, data []byte) {
+				if stream == board.Stdout {
+					_, _ = os.Stdout.Write(data)
+				} else {
+					_, _ = os.Stderr.Write(data)
+				}
+			})
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				return &exitCodeError{code: code}
+			}
+			return nil
+		},
+	}
+	// The flags after the command belong to it.
+	cmd.Flags().SetInterspersed(false)
+	return cmd
+}
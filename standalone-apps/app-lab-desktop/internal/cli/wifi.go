@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"app-lab-desktop/internal/network/wifi"
+
+	"github.com/spf13/cobra"
+)
+
+func newWiFiCommand(flags *globalFlags) *cobra.Command {
+	wifiCmd := &cobra.Command{
+		Use:   "wifi",
+		Short: "Board Wi-Fi",
+	}
+
+	wifiCmd.AddCommand(&cobra.Command{
+		Use:   "scan",
+		Short: "List the Wi-Fi networks visible from the board",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			ssids, err := wifi.ListSSIDs(cmd.Context(), b.Conn())
+			if err != nil {
+				return err
+			}
+			return printJSON(ssids)
+		},
+	})
+
+	wifiCmd.AddCommand(&cobra.Command{
+		Use:   "connect <ssid> [wifi-password]",
+		Short: "Connect the board to a Wi-Fi network",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ssid, password := args[0], ""
+			if len(args) == 2 {
+				password = args[1]
+			}
+
+			b, closeBoard, err := connectBoard(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer closeBoard()
+
+			if err := wifi.Connect(cmd.Context(), b.Conn(), ssid, password); err != nil {
+				return err
+			}
+			status, err := wifi.GetWiFiStatus(cmd.Context(), b.Conn())
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]any{"ssid": ssid, "status": status})
+		},
+	})
+
+	return wifiCmd
+}
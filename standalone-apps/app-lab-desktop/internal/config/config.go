@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const appDirName = "arduino-app-lab"
+
+// filesMu serializes the writes to the config files, so that concurrent updates
+// of the same file never interleave.
+var filesMu sync.Mutex
+
+// Dir returns the directory where the app keeps its local state, creating it if needed.
+// It can be overridden with the ARDUINO_APP_LAB_CONFIG_DIR environment variable.
+func Dir() (string, error) {
+	dir := os.Getenv("ARDUINO_APP_LAB_CONFIG_DIR")
+	if dir == "" {
+		userDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user config dir: %w", err)
+		}
+		dir = filepath.Join(userDir, appDirName)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create config dir: %w", err)
+	}
+	return dir, nil
+}
+
+// Path returns the path of the named file in the config dir.
+func Path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// Load decodes the named JSON file of the config dir into v. A missing file is not
+// an error and leaves v untouched.
+func Load(name string, v any) error {
+	p, err := Path(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// Save encodes v in the named JSON file of the config dir. The file is replaced
+// atomically and is only readable by the current user.
+func Save(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return WriteFile(name, data)
+}
+
+// WriteFile atomically replaces the named file of the config dir with data.
+func WriteFile(name string, data []byte) error {
+	p, err := Path(name)
+	if err != nil {
+		return err
+	}
+
+	filesMu.Lock()
+	defer filesMu.Unlock()
+	return AtomicWriteFile(p, data)
+}
+
+// AtomicWriteFile replaces the file at p with data, only readable by the current
+// user. Readers see either the old or the new content, never a partial write.
+func AtomicWriteFile(p string, data []byte) error {
+	name := filepath.Base(p)
+	tmp, err := os.CreateTemp(filepath.Dir(p), name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
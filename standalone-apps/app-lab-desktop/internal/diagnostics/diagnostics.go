@@ -0,0 +1,219 @@
+package diagnostics
+
+// A diagnostic bundle is a zip with the logs and the state of a board, collected
+// through its connection, together with the logs and the version of the app. Every
+// file is redacted before being written.
+
+import (
+	"app-lab-desktop/internal/applog"
+	"app-lab-desktop/internal/board"
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// maxLogLines bounds the lines of each log taken from the board.
+	maxLogLines = 5000
+	// containerLogLines bounds the lines of each container log.
+	containerLogLines = 2000
+)
+
+// boardFiles are the files of the bundle produced by a command on the board.
+var boardFiles = []struct {
+	name string
+	cmd  string
+	args []string
+}{
+	{"board/buildinfo.txt", "cat", []string{"/etc/buildinfo"}},
+	{"board/journal.txt", "journalctl", []string{"-b", "--no-pager", "-n", fmt.Sprint(maxLogLines)}},
+	{"board/orchestrator.log", "journalctl", []string{"-u", "arduino-app-cli", "--no-pager", "-n", fmt.Sprint(maxLogLines)}},
+	{"board/docker-ps.txt", "docker", []string{"ps", "-a"}},
+	{"board/nmcli-general.txt", "nmcli", []string{"general", "status"}},
+	{"board/nmcli-devices.txt", "nmcli", []string{"device", "status"}},
+	{"board/nmcli-connections.txt", "nmcli", []string{"connection", "show"}},
+	{"board/ip-addr.txt", "ip", []string{"addr"}},
+}
+
+// AppInfo describes the desktop app in the bundle.
+type AppInfo struct {
+	Version     string    `json:"version"`
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	GoVersion   string    `json:"goVersion"`
+	CollectedAt time.Time `json:"collectedAt"`
+	Board       string    `json:"board,omitempty"`
+}
+
+// FileName returns the default name of a bundle collected now.
+func FileName(b *board.Board) string {
+	name := "app-lab-diagnostics"
+	if b != nil && b.Id != "" {
+		name += "-" + sanitize(b.Id)
+	}
+	return name + "-" + time.Now().Format("20060102-150405") + ".zip"
+}
+
+// Collect writes the diagnostic bundle of b, nil to collect only the app data, to
+// dest. The files that cannot be collected are listed in errors.txt instead of
+// failing the whole bundle.
+func Collect(ctx context.Context, b *board.Board, appVersion string, dest string) error {
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create diagnostic bundle: %w", err)
+	}
+	bundle := &bundle{zip: zip.NewWriter(f), now: time.Now(), secrets: board.StoredPasswords()}
+
+	info := AppInfo{
+		Version:     appVersion,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		GoVersion:   runtime.Version(),
+		CollectedAt: bundle.now,
+	}
+	if b != nil {
+		info.Board = b.Id
+	}
+	bundle.addJSON("app/info.json", info)
+	// The board comes first, its Wi-Fi secrets are hidden from the app logs too.
+	if b != nil {
+		bundle.addBoard(ctx, b)
+	}
+	bundle.addAppLogs()
+	if len(bundle.errors) > 0 {
+		bundle.add("errors.txt", []byte(strings.Join(bundle.errors, "\n")+"\n"))
+	}
+
+	if err := bundle.zip.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write diagnostic bundle: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write diagnostic bundle: %w", err)
+	}
+	if ctx.Err() != nil {
+		_ = os.Remove(dest)
+		return ctx.Err()
+	}
+	return nil
+}
+
+type bundle struct {
+	zip    *zip.Writer
+	now    time.Time
+	errors []string
+	// secrets are the board passwords and Wi-Fi keys, redacted literally.
+	secrets []string
+}
+
+func (b *bundle) fail(name string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %s", name, Redact(err.Error(), b.secrets...)))
+}
+
+func (b *bundle) add(name string, data []byte) {
+	w, err := b.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: b.now})
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	if _, err := w.Write([]byte(Redact(string(data), b.secrets...))); err != nil {
+		b.fail(name, err)
+	}
+}
+
+func (b *bundle) addJSON(name string, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	b.add(name, data)
+}
+
+func (b *bundle) addAppLogs() {
+	files, err := applog.Files()
+	if err != nil {
+		b.fail("app/logs", err)
+		return
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			b.fail("app/logs", err)
+			continue
+		}
+		b.add("app/logs/"+filepath.Base(f), data)
+	}
+}
+
+func (b *bundle) addBoard(ctx context.Context, brd *board.Board) {
+	if err := brd.Ping(ctx); err != nil {
+		b.fail("board", err)
+		return
+	}
+
+	if profiles, err := brd.ListWiFiProfiles(ctx); err != nil {
+		b.fail("board/wifi-profiles", err)
+	} else {
+		for _, p := range profiles {
+			b.secrets = append(b.secrets, p.PSK)
+		}
+	}
+	if info, err := brd.GetSystemInfo(ctx); err != nil {
+		b.fail("board/system-info.json", err)
+	} else {
+		b.addJSON("board/system-info.json", info)
+	}
+	for _, file := range boardFiles {
+		if ctx.Err() != nil {
+			return
+		}
+		b.addCommand(ctx, brd, file.name, file.cmd, file.args...)
+	}
+
+	res, err := brd.RunCommand(ctx, "docker", []string{"ps", "-a", "--format", "{{.Names}}"})
+	if err != nil || res.ExitCode != 0 {
+		// Already reported by docker-ps.txt.
+		return
+	}
+	for _, name := range strings.Fields(res.Stdout) {
+		if ctx.Err() != nil {
+			return
+		}
+		b.addCommand(ctx, brd, "board/docker-logs/"+sanitize(name)+".log", "docker", "logs", "--timestamps", "--tail", fmt.Sprint(containerLogLines), name)
+	}
+}
+
+// addCommand adds the output of the command, with its errors appended, since the
+// docker logs of the containers go to both stdout and stderr.
+func (b *bundle) addCommand(ctx context.Context, brd *board.Board, name string, cmd string, args ...string) {
+	res, err := brd.RunCommand(ctx, cmd, args)
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	out := res.Stdout
+	if res.Stderr != "" {
+		out += "\n--- stderr ---\n" + res.Stderr
+	}
+	if res.ExitCode != 0 {
+		b.fail(name, fmt.Errorf("%s exited with %d", cmd, res.ExitCode))
+	}
+	b.add(name, []byte(out))
+}
+
+// sanitize makes s usable in a file name.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, s)
+}
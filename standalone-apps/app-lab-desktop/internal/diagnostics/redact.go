@@ -0,0 +1,57 @@
+package diagnostics
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	redacted = "<redacted>"
+	// minSecretLength is the length under which the known secrets are not replaced
+	// literally, they would hide the rest of the text.
+	minSecretLength = 4
+)
+
+var (
+	// secretAssignment matches "<key>=<value>" and "<key>: <value>", with the key and
+	// the value optionally quoted, for the keys holding secrets: passwords, Wi-Fi
+	// keys (psk, wep-key0...) and tokens.
+	secretAssignment = regexp.MustCompile(`(?i)([\w.-]*(?:password|passwd|passphrase|psk|secret|token|api[_-]?key|private[_-]?key|wep-key\d*)["']?)(\s*[=:]\s*)("[^"]*"|'[^']*'|[^\s,;]+)`)
+	// secretArgument matches the secrets given as "<key> <value>" arguments, as nmcli
+	// takes them, e.g. "nmcli device wifi connect Home password hunter2".
+	secretArgument = regexp.MustCompile(`(?i)(\s(?:[\w-]*\.)?(?:password|psk))(\s+)("[^"]*"|'[^']*'|\S+)`)
+	// urlCredentials matches the password of the URLs with credentials.
+	urlCredentials = regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`)
+	privateKey     = regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// Redact hides the passwords, the Wi-Fi secrets, the tokens and the private keys in s,
+// and the given secrets wherever they appear.
+func Redact(s string, secrets ...string) string {
+	// The longest secrets first, so that one containing another is hidden whole.
+	secrets = slices.SortedFunc(slices.Values(secrets), func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	s = privateKey.ReplaceAllString(s, redacted)
+	s = urlCredentials.ReplaceAllString(s, "${1}"+redacted+"@")
+	s = secretAssignment.ReplaceAllStringFunc(s, redactValue(secretAssignment))
+	return secretArgument.ReplaceAllStringFunc(s, redactValue(secretArgument))
+}
+
+// redactValue returns the replacement of the matches of re, a key, a separator and
+// a value, hiding the value but its quotes.
+func redactValue(re *regexp.Regexp) func(string) string {
+	return func(m string) string {
+		parts := re.FindStringSubmatch(m)
+		key, sep, value := parts[1], parts[2], parts[3]
+		if q := value[0]; q == '"' || q == '\'' {
+			return key + sep + string(q) + redacted + string(q)
+		}
+		return key + sep + redacted
+	}
+}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"app-lab-desktop/internal/sshconn"
 	"app-lab-desktop/internal/tunnel"
 	"errors"
 
@@ -25,13 +26,33 @@ func ChainErrorMiddleware(middlewares []ErrorMiddleware) options.ErrorFormatter
 	return f
 }
 
-func TunnelSSHAuthFailedMiddleware() ErrorMiddleware {
+// TunnelSSHAuthFailedMiddleware reports the SSH authentication failures with a
+// structured error, after calling onAuthFailed, if not nil, to invalidate the
+// credentials that have been rejected.
+func TunnelSSHAuthFailedMiddleware(onAuthFailed func(err error)) ErrorMiddleware {
 	return func(next options.ErrorFormatter) options.ErrorFormatter {
 		return func(err error) any {
 			if errors.Is(err, ssh.ErrAuthFailed) {
+				if onAuthFailed != nil {
+					onAuthFailed(err)
+				}
 				return tunnel.NewSSHErrorAuthFailed(err)
 			}
 			return next(err)
 		}
 	}
 }
+
+// TunnelSSHHostKeyMismatchMiddleware reports a board whose host key does not match
+// the pinned one with a structured error, so that the user can review it.
+func TunnelSSHHostKeyMismatchMiddleware() ErrorMiddleware {
+	return func(next options.ErrorFormatter) options.ErrorFormatter {
+		return func(err error) any {
+			var mismatch *sshconn.HostKeyMismatchError
+			if errors.As(err, &mismatch) {
+				return tunnel.NewSSHErrorHostKeyMismatch(err, mismatch)
+			}
+			return next(err)
+		}
+	}
+}
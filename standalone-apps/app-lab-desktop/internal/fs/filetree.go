@@ -43,24 +43,30 @@ func GetFileTree(rootPath string, conn remote.RemoteConn) (*FSNode, error) {
 	return BuildFileTree(fs, []string{".DS_Store", "Thumbs.db", ".cache"})
 }
 
-func buildFileTreeRecursive(fss fs.FS, currentPath string, ignoreFn func(string) bool) (*FSNode, error) {
-	if ignoreFn(currentPath) {
-		return nil, nil
-	}
-
-	f, err := fss.Open(currentPath)
+// ListDir returns the direct children of rootPath, without descending into subdirectories.
+func ListDir(rootPath string, conn remote.RemoteConn) ([]FSNode, error) {
+	entries, err := fs.ReadDir(getFS(rootPath, conn), ".")
 	if err != nil {
 		return nil, err
 	}
 
-	info, err := f.Stat()
-	if err != nil {
-		return nil, err
+	sortDirEntries(entries)
+
+	nodes := make([]FSNode, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		nodes = append(nodes, newFSNode(path.Join(rootPath, entry.Name()), info))
 	}
+	return nodes, nil
+}
 
+func newFSNode(p string, info fs.FileInfo) FSNode {
 	node := FSNode{
 		Name:       info.Name(),
-		Path:       currentPath,
+		Path:       p,
 		Size:       info.Size(),
 		IsDir:      info.IsDir(),
 		CreatedAt:  info.ModTime().Format(time.RFC3339),
@@ -68,7 +74,7 @@ func buildFileTreeRecursive(fss fs.FS, currentPath string, ignoreFn func(string)
 	}
 
 	if !node.IsDir {
-		ext := path.Ext(currentPath)
+		ext := path.Ext(p)
 		mimeType := mime.TypeByExtension(ext)
 		if mimeType == "" {
 			mimeType = "application/octet-stream"
@@ -76,6 +82,27 @@ func buildFileTreeRecursive(fss fs.FS, currentPath string, ignoreFn func(string)
 
 		node.Extension = &ext
 		node.MimeType = &mimeType
+	}
+	return node
+}
+
+func buildFileTreeRecursive(fss fs.FS, currentPath string, ignoreFn func(string) bool) (*FSNode, error) {
+	if ignoreFn(currentPath) {
+		return nil, nil
+	}
+
+	f, err := fss.Open(currentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	node := newFSNode(currentPath, info)
+	if !node.IsDir {
 		return &node, nil
 	}
 
@@ -16,7 +16,7 @@ import (
 
 type fileContentAssetMiddleware struct {
 	ctxHolder     *context.Holder
-	selectedBoard *board.Board
+	selectedBoard func() *board.Board
 }
 
 var _ http.Handler = (*fileContentAssetMiddleware)(nil)
@@ -35,7 +35,7 @@ func (m *fileContentAssetMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Re
 
 	dir, file := path.Dir(p), path.Base(p)
 
-	f, err := getFS(dir, m.selectedBoard.Conn).Open(file)
+	f, err := getFS(dir, m.selectedBoard().Conn()).Open(file)
 	if err != nil {
 		if errors.Is(err, fs.ErrInvalid) {
 			w.WriteHeader(http.StatusNotFound)
@@ -55,7 +55,9 @@ func (m *fileContentAssetMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Re
 	}
 }
 
-func FileContentAssetMiddleware(ctxHolder *context.Holder, selectedBoard *board.Board) assetserver.Middleware {
+// FileContentAssetMiddleware serves the files of the board returned by selectedBoard,
+// which is called on every request so that it follows board switches.
+func FileContentAssetMiddleware(ctxHolder *context.Holder, selectedBoard func() *board.Board) assetserver.Middleware {
 	m := &fileContentAssetMiddleware{
 		ctxHolder:     ctxHolder,
 		selectedBoard: selectedBoard,
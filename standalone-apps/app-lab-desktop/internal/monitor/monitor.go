@@ -0,0 +1,228 @@
+package monitor
+
+import (
+	"app-lab-desktop/internal/board"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval = 5 * time.Second
+	// MinInterval is the shortest sampling interval, reading the container usage
+	// takes already about two seconds.
+	MinInterval = 2 * time.Second
+	// HistorySize is the number of samples kept per board.
+	HistorySize = 360
+)
+
+// Sample is the resource usage of a board at a point in time.
+type Sample struct {
+	Time time.Time `json:"time"`
+	// CPUPercent is the CPU usage since the previous sample, -1 for the first one.
+	CPUPercent        float64                `json:"cpuPercent"`
+	Load              [3]float64             `json:"load"`
+	Memory            board.MemoryInfo       `json:"memory"`
+	Temperatures      []board.Temperature    `json:"temperatures"`
+	CPUFrequenciesMHz []float64              `json:"cpuFrequenciesMHz"`
+	Containers        []board.ContainerUsage `json:"containers"`
+}
+
+// Event reports a new sample of a board.
+type Event struct {
+	BoardID string `json:"boardId"`
+	Sample  Sample `json:"sample"`
+}
+
+// Manager samples the resources of the boards that are monitored, one subscription
+// per board.
+type Manager struct {
+	onEvent func(Event)
+
+	mu   sync.Mutex
+	subs map[string]*subscription
+}
+
+type subscription struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	interval time.Duration
+	// history is handed over to the subscription replacing this one.
+	history *ring
+}
+
+func NewManager(onEvent func(Event)) *Manager {
+	return &Manager{
+		onEvent: onEvent,
+		subs:    make(map[string]*subscription),
+	}
+}
+
+// Start samples the board every interval until Stop is called or ctx, usually the
+// one of the board session, is done. Starting a monitored board again changes its
+// interval and keeps its history.
+func (m *Manager) Start(ctx context.Context, boardID string, b *board.Board, interval time.Duration) error {
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	if interval < MinInterval {
+		return fmt.Errorf("interval must be at least %s", MinInterval)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &subscription{cancel: cancel, done: make(chan struct{}), interval: interval}
+
+	// The previous subscription is replaced at once, so that concurrent starts
+	// leave a single one running.
+	m.mu.Lock()
+	prev := m.subs[boardID]
+	if prev != nil {
+		prev.cancel()
+		sub.history = prev.history
+	} else {
+		sub.history = newRing(HistorySize)
+	}
+	m.subs[boardID] = sub
+	m.mu.Unlock()
+
+	go func() {
+		defer close(sub.done)
+		defer func() {
+			m.mu.Lock()
+			if m.subs[boardID] == sub {
+				delete(m.subs, boardID)
+			}
+			m.mu.Unlock()
+		}()
+		if prev != nil {
+			// The sampling in progress ends before the history is written again.
+			<-prev.done
+		}
+		m.run(ctx, boardID, b, sub)
+	}()
+	return nil
+}
+
+// Stop stops the monitoring of the board and waits for the sampling in progress.
+func (m *Manager) Stop(boardID string) error {
+	if m.stop(boardID) == nil {
+		return fmt.Errorf("board %s is not monitored", boardID)
+	}
+	return nil
+}
+
+func (m *Manager) stop(boardID string) *subscription {
+	m.mu.Lock()
+	sub, ok := m.subs[boardID]
+	delete(m.subs, boardID)
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	sub.cancel()
+	<-sub.done
+	return sub
+}
+
+// StopAll stops the monitoring of every board.
+func (m *Manager) StopAll() {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.subs))
+	for id := range m.subs {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+	for _, id := range ids {
+		m.stop(id)
+	}
+}
+
+// History returns the last samples of a monitored board, the oldest first.
+func (m *Manager) History(boardID string) ([]Sample, error) {
+	m.mu.Lock()
+	sub, ok := m.subs[boardID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("board %s is not monitored", boardID)
+	}
+	return sub.history.list(), nil
+}
+
+func (m *Manager) run(ctx context.Context, boardID string, b *board.Board, sub *subscription) {
+	ticker := time.NewTicker(sub.interval)
+	defer ticker.Stop()
+
+	var prev *board.CPUTimes
+	for {
+		usage, err := b.ReadResources(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("failed to sample board resources", "board", boardID, "err", err)
+		} else {
+			s := newSample(usage, prev)
+			prev = &usage.CPU
+			sub.history.add(s)
+			m.onEvent(Event{BoardID: boardID, Sample: s})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func newSample(usage board.ResourceUsage, prev *board.CPUTimes) Sample {
+	return Sample{
+		Time:              usage.Time,
+		CPUPercent:        cpuPercent(prev, usage.CPU),
+		Load:              usage.Load,
+		Memory:            usage.Memory,
+		Temperatures:      usage.Temperatures,
+		CPUFrequenciesMHz: usage.CPUFrequenciesMHz,
+		Containers:        usage.Containers,
+	}
+}
+
+func cpuPercent(prev *board.CPUTimes, cur board.CPUTimes) float64 {
+	if prev == nil || cur.Total <= prev.Total || cur.Busy < prev.Busy {
+		return -1
+	}
+	return float64(cur.Busy-prev.Busy) / float64(cur.Total-prev.Total) * 100
+}
+
+// ring keeps the last samples, it is safe for concurrent use.
+type ring struct {
+	mu      sync.Mutex
+	samples []Sample
+	next    int
+	full    bool
+}
+
+func newRing(size int) *ring {
+	return &ring{samples: make([]Sample, size)}
+}
+
+func (r *ring) add(s Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *ring) list() []Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]Sample{}, r.samples[:r.next]...)
+	}
+	return append(append([]Sample{}, r.samples[r.next:]...), r.samples[:r.next]...)
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"app-lab-desktop/internal/board"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	r := newRing(3)
+	if got := r.list(); len(got) != 0 {
+		t.Fatalf("expected an empty history, got %d samples", len(got))
+	}
+
+	start := time.Unix(0, 0)
+	for i := range 5 {
+		r.add(Sample{Time: start.Add(time.Duration(i) * time.Second)})
+	}
+	got := r.list()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(got))
+	}
+	for i, s := range got {
+		if expected := start.Add(time.Duration(i+2) * time.Second); !s.Time.Equal(expected) {
+			t.Errorf("sample %d: expected %v, got %v", i, expected, s.Time)
+		}
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		prev     *board.CPUTimes
+		cur      board.CPUTimes
+		expected float64
+	}{
+		{name: "first sample", prev: nil, cur: board.CPUTimes{Busy: 10, Total: 100}, expected: -1},
+		{name: "usage", prev: &board.CPUTimes{Busy: 10, Total: 100}, cur: board.CPUTimes{Busy: 60, Total: 300}, expected: 25},
+		{name: "counters reset", prev: &board.CPUTimes{Busy: 10, Total: 100}, cur: board.CPUTimes{Busy: 5, Total: 50}, expected: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cpuPercent(tt.prev, tt.cur); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestConcurrentStartsKeepOneSubscription(t *testing.T) {
+	m := NewManager(func(Event) {})
+	defer m.StopAll()
+	// The board is not connected, its samples fail.
+	b := board.NewWithDialer("fake", nil)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Start(context.Background(), "fake", b, MinInterval); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	n := len(m.subs)
+	m.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("expected 1 subscription, got %d", n)
+	}
+	if err := m.Stop("fake"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.History("fake"); err == nil {
+		t.Errorf("expected the board not to be monitored once stopped")
+	}
+}
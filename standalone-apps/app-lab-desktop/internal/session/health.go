@@ -0,0 +1,153 @@
+package session
+
+import (
+	"app-lab-desktop/internal/tunnel"
+	"log/slog"
+	"time"
+)
+
+type State string
+
+const (
+	Connected    State = "connected"
+	Reconnecting State = "reconnecting"
+	// Lost means that the board could not be reconnected for a while, the monitor
+	// keeps retrying until the session is closed.
+	Lost State = "lost"
+	// Offline means that the board is expected to be unreachable, e.g. while it reboots,
+	// and is reconnected when it comes back.
+	Offline State = "offline"
+)
+
+// The delays are variables so that the tests can shorten them.
+var (
+	healthCheckInterval = 5 * time.Second
+	reconnectMinBackoff = 1 * time.Second
+	reconnectMaxBackoff = 30 * time.Second
+	reconnectTimeout    = 2 * time.Minute
+)
+
+// monitor pings the board of the session and re-establishes its connection when
+// the board stops answering, e.g. while it reboots after an update or when the USB
+// cable is unplugged. It returns when the session is closed.
+func (m *Manager) monitor(s *Session) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// The board going offline on purpose is reconnected by the power actions.
+		if s.State() != Connected {
+			continue
+		}
+		err := s.Board.Ping(s.ctx)
+		if err == nil || s.ctx.Err() != nil {
+			continue
+		}
+		if !m.transition(s, Connected, Reconnecting) {
+			continue
+		}
+		slog.Warn("board connection lost, reconnecting", "board", s.Board.Id, "err", err)
+		m.reconnect(s, reconnectTimeout)
+	}
+}
+
+// reconnect retries to connect the board with an exponential backoff until it
+// succeeds or the session is closed. The board is marked as lost after lostAfter,
+// if not zero.
+func (m *Manager) reconnect(s *Session, lostAfter time.Duration) {
+	start := time.Now()
+	backoff := reconnectMinBackoff
+	// The tunnels are closed by the first attempt, they are listed beforehand.
+	tunnels := s.Board.SnapshotTunnels()
+
+	for {
+		err := m.reconnectOnce(s, tunnels)
+		if err == nil {
+			slog.Info("board reconnected", "board", s.Board.Id)
+			m.setState(s, Connected)
+			return
+		}
+		slog.Warn("failed to reconnect board", "board", s.Board.Id, "err", err)
+		if lostAfter > 0 && time.Since(start) > lostAfter {
+			m.setState(s, Lost)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, reconnectMaxBackoff)
+	}
+}
+
+// reconnectOnce connects the board again, and opens its tunnels again: the
+// orchestrator one, the forwards and then the other tunnels of the snapshot.
+func (m *Manager) reconnectOnce(s *Session, tunnels tunnel.Snapshot) error {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	// The session may have been closed while waiting for the lock, a connection
+	// established now would never be closed.
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	// The board may come back elsewhere, e.g. with a new DHCP lease after a reboot. The
+	// connection checks its identity anyway: the pinned host key or the USB serial.
+	if m.resolve != nil {
+		if found := m.resolve(s.Board.Id); found != nil {
+			if err := s.Board.Relocate(found); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.Board.Reconnect(s.ctx, s.password); err != nil {
+		return err
+	}
+	s.Board.RestoreForwards(s.ctx)
+	if dropped := s.Board.RestoreTunnels(s.ctx, tunnels); len(dropped) > 0 && s.ctx.Err() == nil {
+		m.onEvent(Event{Type: TunnelsDropped, Board: s.Board, Tunnels: dropped})
+	}
+	return nil
+}
+
+// State returns the state of the connection to the board of the session.
+func (s *Session) State() State {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.state
+}
+
+// transition changes the state of the session only if it is from, so that a single
+// goroutine handles a connection loss.
+func (m *Manager) transition(s *Session, from State, to State) bool {
+	s.stateMu.Lock()
+	if s.state != from {
+		s.stateMu.Unlock()
+		return false
+	}
+	s.state = to
+	s.stateMu.Unlock()
+
+	if from != to && s.ctx.Err() == nil {
+		m.onEvent(Event{Type: StateChanged, Board: s.Board, State: to})
+	}
+	return true
+}
+
+func (m *Manager) setState(s *Session, state State) {
+	s.stateMu.Lock()
+	changed := s.state != state
+	s.state = state
+	s.stateMu.Unlock()
+
+	if changed && s.ctx.Err() == nil {
+		m.onEvent(Event{Type: StateChanged, Board: s.Board, State: state})
+	}
+}
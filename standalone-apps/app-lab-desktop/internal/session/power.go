@@ -0,0 +1,107 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	// goingDownTimeout bounds the wait for the board to stop answering once asked to
+	// reboot or power off, systemd stops every service first.
+	goingDownTimeout   = 2 * time.Minute
+	goingDownInterval  = 2 * time.Second
+	rebootTimeout      = 5 * time.Minute
+	serviceRestartWait = 2 * time.Second
+)
+
+// Reboot reboots the board of the session. The session is offline until the board
+// comes back and is reconnected, together with its tunnels, in the background.
+func (m *Manager) Reboot(s *Session) error {
+	return m.goOffline(s, s.Board.Reboot, rebootTimeout)
+}
+
+// Shutdown powers off the board of the session. The session stays offline, and is
+// reconnected whenever the board is powered on again, until it is closed.
+func (m *Manager) Shutdown(s *Session) error {
+	return m.goOffline(s, s.Board.Shutdown, 0)
+}
+
+// RestartService restarts a service of the board. The session is offline during the
+// restart and reconnected afterwards if the connection did not survive it, e.g. when
+// restarting the SSH server.
+func (m *Manager) RestartService(s *Session, name string) error {
+	if !m.transition(s, Connected, Offline) {
+		return fmt.Errorf("board %s is not connected", s.Board.Id)
+	}
+
+	err := s.Board.RestartService(s.ctx, name)
+	if err != nil && s.Board.Ping(s.ctx) == nil {
+		m.transition(s, Offline, Connected)
+		return err
+	}
+
+	if err == nil {
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(serviceRestartWait):
+		}
+		if s.Board.Ping(s.ctx) == nil {
+			m.transition(s, Offline, Connected)
+			return nil
+		}
+	}
+	// The connection may have been lost by the restart, or before the restart could
+	// report its outcome, which is then unknown.
+	slog.Info("board connection lost by the service restart, reconnecting", "board", s.Board.Id, "service", name)
+	go m.reconnect(s, reconnectTimeout)
+	return err
+}
+
+// goOffline runs action, that takes the board down, waits for the board to stop
+// answering and then reconnects it in the background.
+func (m *Manager) goOffline(s *Session, action func(context.Context) error, lostAfter time.Duration) error {
+	if !m.transition(s, Connected, Offline) {
+		return fmt.Errorf("board %s is not connected", s.Board.Id)
+	}
+
+	// The connection may drop before the action answers, only a board that still
+	// answers did not take it.
+	if err := action(s.ctx); err != nil && s.Board.Ping(s.ctx) == nil {
+		m.transition(s, Offline, Connected)
+		return err
+	}
+	if err := m.waitDown(s); err != nil {
+		m.transition(s, Offline, Connected)
+		return err
+	}
+
+	slog.Info("board went offline, waiting for it to come back", "board", s.Board.Id)
+	go m.reconnect(s, lostAfter)
+	return nil
+}
+
+// waitDown waits for the board of the session to stop answering.
+func (m *Manager) waitDown(s *Session) error {
+	ctx, cancel := context.WithTimeout(s.ctx, goingDownTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(goingDownInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if s.ctx.Err() != nil {
+				return s.ctx.Err()
+			}
+			return errors.New("the board did not go down")
+		case <-ticker.C:
+		}
+		if s.Board.Ping(ctx) != nil && ctx.Err() == nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package session
+
+import (
+	"app-lab-desktop/internal/board"
+	appcontext "app-lab-desktop/internal/context"
+	"app-lab-desktop/internal/tunnel"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Session is the live connection to a board. Operations started against the board
+// should use the session context: closing the session cancels them together with
+// the tunnels and the connection.
+type Session struct {
+	Board    *board.Board
+	ctx      context.Context
+	cancel   context.CancelFunc
+	password string
+
+	// connMu guards the connection of the board while the health monitor
+	// re-establishes it.
+	connMu  sync.Mutex
+	stateMu sync.Mutex
+	state   State
+}
+
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Session) close(ctx context.Context) {
+	s.cancel()
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	s.Board.Close(ctx)
+}
+
+type EventType string
+
+const (
+	Opened    EventType = "opened"
+	Closed    EventType = "closed"
+	Activated EventType = "activated"
+	// StateChanged is sent by the health monitor when the state of the connection changes.
+	StateChanged EventType = "state"
+	// TunnelsDropped is sent when tunnels could not be opened again on reconnection.
+	TunnelsDropped EventType = "tunnels-dropped"
+)
+
+type Event struct {
+	Type  EventType    `json:"type"`
+	Board *board.Board `json:"board"`
+	State State        `json:"state,omitempty"`
+	// Tunnels are the tunnels dropped, for TunnelsDropped.
+	Tunnels []tunnel.Info `json:"tunnels,omitempty"`
+}
+
+// Manager owns the sessions of the connected boards. One of them is the active
+// session, targeted by every call that does not name a board.
+type Manager struct {
+	ctxHolder *appcontext.Holder
+	onEvent   func(Event)
+	noop      *board.Board
+	// resolve finds the board with the given id among the detected ones, nil if it
+	// is not detected.
+	resolve func(id string) *board.Board
+
+	// connectMu serializes the connections, that may take a while, while mu only
+	// guards the sessions so that readers are never blocked.
+	connectMu sync.Mutex
+	mu        sync.RWMutex
+	sessions  map[string]*Session
+	activeID  string
+}
+
+// NewManager returns a manager notifying onEvent of the session changes. resolve, if
+// not nil, finds a board among the detected ones by id, so that a board coming back
+// with another address is reconnected.
+func NewManager(ctxHolder *appcontext.Holder, onEvent func(Event), resolve func(id string) *board.Board) *Manager {
+	return &Manager{
+		ctxHolder: ctxHolder,
+		onEvent:   onEvent,
+		resolve:   resolve,
+		noop:      board.Noop(),
+		sessions:  make(map[string]*Session),
+	}
+}
+
+// Active returns the active session, or a session on a Noop board if none is active.
+func (m *Manager) Active() *Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if s, ok := m.sessions[m.activeID]; ok {
+		return s
+	}
+	return &Session{Board: m.noop, ctx: m.ctxHolder.Get(), cancel: func() {}}
+}
+
+// Board returns the board of the active session, it is meant to be used by
+// long-lived handlers that must always target the current selection.
+func (m *Manager) Board() *board.Board {
+	return m.Active().Board
+}
+
+// Get returns the session of the connected board with the given id, or the active
+// session if id is empty.
+func (m *Manager) Get(id string) (*Session, error) {
+	if id == "" {
+		return m.Active(), nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("board %s is not connected", id)
+	}
+	return s, nil
+}
+
+// Boards returns the connected boards.
+func (m *Manager) Boards() []*board.Board {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	boards := make([]*board.Board, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		boards = append(boards, s.Board)
+	}
+	sort.Slice(boards, func(i, j int) bool {
+		return boards[i].Id < boards[j].Id
+	})
+	return boards
+}
+
+// Connect opens a session on b, unless already connected, without changing the
+// active one. The first connected board becomes the active one.
+func (m *Manager) Connect(b *board.Board, password string) error {
+	m.connectMu.Lock()
+	defer m.connectMu.Unlock()
+
+	_, err := m.connect(b, password)
+	return err
+}
+
+// Switch makes b the active board, connecting it if needed, and closes the session
+// that was active before. The previous session is closed first, so that a failed
+// connection never leaves two sessions of the same board competing for its ports.
+func (m *Manager) Switch(b *board.Board, password string) error {
+	m.connectMu.Lock()
+	defer m.connectMu.Unlock()
+
+	m.mu.RLock()
+	previousID := m.activeID
+	m.mu.RUnlock()
+	if previousID != b.Id {
+		m.disconnect(m.ctxHolder.Get(), previousID)
+	}
+
+	if _, err := m.connect(b, password); err != nil {
+		return err
+	}
+	return m.SetActive(b.Id)
+}
+
+// Attach adds b, that must be already connected, and makes it the active board.
+func (m *Manager) Attach(b *board.Board) {
+	m.connectMu.Lock()
+	defer m.connectMu.Unlock()
+
+	m.add(m.newSession(b))
+	_ = m.SetActive(b.Id)
+}
+
+// SetActive makes the connected board with the given id the active one.
+func (m *Manager) SetActive(id string) error {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("board %s is not connected", id)
+	}
+	changed := m.activeID != id
+	m.activeID = id
+	m.mu.Unlock()
+
+	if changed {
+		m.onEvent(Event{Type: Activated, Board: s.Board})
+	}
+	return nil
+}
+
+// Disconnect closes the session of the board with the given id. If it was the
+// active one, no board is active anymore.
+func (m *Manager) Disconnect(ctx context.Context, id string) error {
+	m.connectMu.Lock()
+	defer m.connectMu.Unlock()
+
+	if !m.disconnect(ctx, id) {
+		return fmt.Errorf("board %s is not connected", id)
+	}
+	return nil
+}
+
+// Close closes every session.
+func (m *Manager) Close(ctx context.Context) {
+	m.connectMu.Lock()
+	defer m.connectMu.Unlock()
+
+	for _, b := range m.Boards() {
+		m.disconnect(ctx, b.Id)
+	}
+}
+
+func (m *Manager) connect(b *board.Board, password string) (*Session, error) {
+	m.mu.RLock()
+	s, ok := m.sessions[b.Id]
+	m.mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	s = m.newSession(b.Clone())
+	s.password = password
+	if err := s.Board.EstablishConnection(s.ctx, password); err != nil {
+		s.close(m.ctxHolder.Get())
+		return nil, fmt.Errorf("failed to connect to board: %w", err)
+	}
+	s.Board.RestoreForwards(s.ctx)
+	m.add(s)
+	go m.monitor(s)
+	return s, nil
+}
+
+func (m *Manager) newSession(b *board.Board) *Session {
+	ctx, cancel := context.WithCancel(m.ctxHolder.Get())
+	return &Session{Board: b, ctx: ctx, cancel: cancel, state: Connected}
+}
+
+func (m *Manager) add(s *Session) {
+	m.mu.Lock()
+	m.sessions[s.Board.Id] = s
+	if m.activeID == "" {
+		m.activeID = s.Board.Id
+	}
+	m.mu.Unlock()
+	m.onEvent(Event{Type: Opened, Board: s.Board})
+}
+
+func (m *Manager) disconnect(ctx context.Context, id string) bool {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	delete(m.sessions, id)
+	if m.activeID == id {
+		m.activeID = ""
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	s.close(ctx)
+	m.onEvent(Event{Type: Closed, Board: s.Board})
+	return true
+}
@@ -0,0 +1,298 @@
+package session
+
+import (
+	"app-lab-desktop/internal/board"
+	appcontext "app-lab-desktop/internal/context"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+)
+
+var errUnreachable = errors.New("board unreachable")
+
+// fakeBoard is a board reached through fakeConn connections, that fail while it is down.
+type fakeBoard struct {
+	up    atomic.Bool
+	dials atomic.Int32
+	// downOn takes the board down when a command containing it runs.
+	downOn string
+	// failOn makes the commands containing it exit with 1.
+	failOn string
+}
+
+func (f *fakeBoard) dial(context.Context) (remote.RemoteConn, error) {
+	f.dials.Add(1)
+	if !f.up.Load() {
+		return nil, errUnreachable
+	}
+	return &fakeConn{board: f}, nil
+}
+
+type fakeConn struct {
+	remote.FS
+	board *fakeBoard
+}
+
+func (c *fakeConn) GetCmd(cmd string, args ...string) remote.Cmder {
+	return &fakeCmder{board: c.board}
+}
+
+func (c *fakeConn) Forward(context.Context, int, int) error { return nil }
+
+func (c *fakeConn) ForwardKillAll(context.Context) error { return nil }
+
+func (c *fakeConn) Close() error { return nil }
+
+type fakeCmder struct {
+	board *fakeBoard
+}
+
+func (c *fakeCmder) Run(context.Context) error {
+	if !c.board.up.Load() {
+		return errUnreachable
+	}
+	return nil
+}
+
+func (c *fakeCmder) Output(ctx context.Context) ([]byte, error) {
+	return nil, c.Run(ctx)
+}
+
+// Interactive runs the script written on stdin, as a shell printing its pid first
+// and exiting with 0.
+func (c *fakeCmder) Interactive() (io.WriteCloser, io.Reader, io.Reader, remote.Closer, error) {
+	if !c.board.up.Load() {
+		return nil, nil, nil, nil, errUnreachable
+	}
+	stdin := &scriptWriter{board: c.board}
+	closer := func() error {
+		if c.board.failOn != "" && strings.Contains(stdin.String(), c.board.failOn) {
+			return exitError(1)
+		}
+		return nil
+	}
+	return stdin, strings.NewReader(""), strings.NewReader("1\n"), closer, nil
+}
+
+type exitError int
+
+func (e exitError) Error() string { return "exit status " + strconv.Itoa(int(e)) }
+
+func (e exitError) ExitCode() int { return int(e) }
+
+type scriptWriter struct {
+	strings.Builder
+	board *fakeBoard
+}
+
+func (w *scriptWriter) Close() error {
+	if w.board.downOn != "" && strings.Contains(w.String(), w.board.downOn) {
+		w.board.up.Store(false)
+	}
+	return nil
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	states []State
+}
+
+func (r *recorder) onEvent(e Event) {
+	if e.Type != StateChanged {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, e.State)
+}
+
+func (r *recorder) get() []State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]State(nil), r.states...)
+}
+
+// waitState waits for the session to reach the expected state.
+func waitState(t *testing.T, s *Session, expected State) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.State() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected state %s, got %s", expected, s.State())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestMain shortens the delays once, the goroutines of a test may outlive it.
+func TestMain(m *testing.M) {
+	healthCheckInterval, reconnectMinBackoff, reconnectMaxBackoff = 10*time.Millisecond, 10*time.Millisecond, 20*time.Millisecond
+	goingDownTimeout, goingDownInterval, serviceRestartWait = 100*time.Millisecond, 10*time.Millisecond, 10*time.Millisecond
+	os.Exit(m.Run())
+}
+
+func connectFake(t *testing.T, fake *fakeBoard) (*Manager, *Session, *recorder) {
+	t.Helper()
+	return connectFakeWithResolver(t, fake, nil)
+}
+
+func connectFakeWithResolver(t *testing.T, fake *fakeBoard, resolve func(string) *board.Board) (*Manager, *Session, *recorder) {
+	t.Helper()
+	t.Setenv("ARDUINO_APP_LAB_CONFIG_DIR", t.TempDir())
+	fake.up.Store(true)
+
+	rec := &recorder{}
+	m := NewManager(appcontext.NewHolder(), rec.onEvent, resolve)
+	b := board.NewWithDialer("fake:1", fake.dial)
+	if err := m.Connect(b, ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close(context.Background()) })
+
+	s, err := m.Get(b.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, s, rec
+}
+
+func TestMonitorReconnects(t *testing.T) {
+	fake := &fakeBoard{}
+	_, s, rec := connectFake(t, fake)
+
+	fake.up.Store(false)
+	waitState(t, s, Reconnecting)
+	fake.up.Store(true)
+	waitState(t, s, Connected)
+
+	if err := s.Board.Ping(context.Background()); err != nil {
+		t.Errorf("expected the board to answer, got %v", err)
+	}
+	if n := fake.dials.Load(); n < 2 {
+		t.Errorf("expected the board to be dialed again, got %d dials", n)
+	}
+	expected := []State{Reconnecting, Connected}
+	if got := rec.get(); !slices.Equal(got, expected) {
+		t.Errorf("expected states %v, got %v", expected, got)
+	}
+}
+
+func TestReconnectRestoresTunnels(t *testing.T) {
+	fake := &fakeBoard{}
+	_, s, _ := connectFake(t, fake)
+
+	if _, err := s.Board.OpenTunnel(context.Background(), "app", 7000); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.up.Store(false)
+	waitState(t, s, Reconnecting)
+	fake.up.Store(true)
+	waitState(t, s, Connected)
+
+	tunnels := s.Board.ListTunnels()
+	if len(tunnels) != 1 || tunnels[0].Tag != "app" || tunnels[0].BoardPort != 7000 {
+		t.Errorf("expected the app tunnel to be open again, got %+v", tunnels)
+	}
+}
+
+func TestRebootReconnects(t *testing.T) {
+	fake := &fakeBoard{downOn: "reboot"}
+	m, s, rec := connectFake(t, fake)
+
+	if err := m.Reboot(s); err != nil {
+		t.Fatal(err)
+	}
+	if state := s.State(); state != Offline {
+		t.Fatalf("expected state %s, got %s", Offline, state)
+	}
+	// A second power action is refused while the board is down.
+	if err := m.Shutdown(s); err == nil {
+		t.Errorf("expected an error while the board is offline")
+	}
+
+	fake.up.Store(true)
+	waitState(t, s, Connected)
+	expected := []State{Offline, Connected}
+	if got := rec.get(); !slices.Equal(got, expected) {
+		t.Errorf("expected states %v, got %v", expected, got)
+	}
+}
+
+func TestRebootNotTaken(t *testing.T) {
+	// The board keeps answering, as if the reboot had been ignored.
+	fake := &fakeBoard{}
+	m, s, _ := connectFake(t, fake)
+
+	if err := m.Reboot(s); err == nil {
+		t.Errorf("expected an error when the board does not go down")
+	}
+	if state := s.State(); state != Connected {
+		t.Errorf("expected state %s, got %s", Connected, state)
+	}
+}
+
+func TestDisconnectStopsReconnection(t *testing.T) {
+	fake := &fakeBoard{}
+	m, s, _ := connectFake(t, fake)
+
+	fake.up.Store(false)
+	waitState(t, s, Reconnecting)
+	if err := m.Disconnect(context.Background(), s.Board.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	dials := fake.dials.Load()
+	fake.up.Store(true)
+	time.Sleep(100 * time.Millisecond)
+	if n := fake.dials.Load(); n > dials+1 {
+		t.Errorf("expected no reconnection after the session is closed, got %d dials", n-dials)
+	}
+	if _, ok := s.Board.Conn().(*fakeConn); ok {
+		t.Errorf("expected the closed session to hold no connection")
+	}
+}
+
+func TestReconnectChecksIdentity(t *testing.T) {
+	fake := &fakeBoard{}
+	var found atomic.Pointer[board.Board]
+	found.Store(board.NewWithDialer("fake:2", fake.dial))
+	_, s, _ := connectFakeWithResolver(t, fake, func(string) *board.Board { return found.Load() })
+
+	// Another board is found in place of the lost one, it is not connected.
+	fake.up.Store(false)
+	waitState(t, s, Reconnecting)
+	fake.up.Store(true)
+	time.Sleep(100 * time.Millisecond)
+	if state := s.State(); state != Reconnecting {
+		t.Fatalf("expected state %s, got %s", Reconnecting, state)
+	}
+
+	found.Store(board.NewWithDialer("fake:1", fake.dial))
+	waitState(t, s, Connected)
+}
+
+func TestRestartServiceLosingConnection(t *testing.T) {
+	// The restart kills the connection before reporting its outcome.
+	fake := &fakeBoard{downOn: "restart", failOn: "restart"}
+	m, s, _ := connectFake(t, fake)
+
+	if err := m.RestartService(s, "ssh"); err == nil {
+		t.Errorf("expected the restart error")
+	}
+	if state := s.State(); state != Offline {
+		t.Fatalf("expected state %s, got %s", Offline, state)
+	}
+	fake.up.Store(true)
+	waitState(t, s, Connected)
+}
@@ -0,0 +1,92 @@
+package sshconn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+	"golang.org/x/crypto/ssh"
+)
+
+type cmder struct {
+	client  *ssh.Client
+	command string
+}
+
+var _ remote.Cmder = (*cmder)(nil)
+
+func (c *cmder) Run(ctx context.Context) error {
+	_, err := c.Output(ctx)
+	return err
+}
+
+func (c *cmder) Output(ctx context.Context) ([]byte, error) {
+	s, err := c.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session: %w", err)
+	}
+	defer s.Close()
+
+	var stdout, stderr bytes.Buffer
+	s.Stdout = &stdout
+	s.Stderr = &stderr
+	if err := s.Start(c.command); err != nil {
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return stdout.Bytes(), fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.Bytes(), nil
+	case <-ctx.Done():
+		_ = s.Signal(ssh.SIGKILL)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *cmder) Interactive() (io.WriteCloser, io.Reader, io.Reader, remote.Closer, error) {
+	s, err := c.client.NewSession()
+	if err != nil {
+		return interactiveErr(fmt.Errorf("failed to open session: %w", err))
+	}
+
+	stdin, err := s.StdinPipe()
+	if err != nil {
+		s.Close()
+		return interactiveErr(err)
+	}
+	stdout, err := s.StdoutPipe()
+	if err != nil {
+		s.Close()
+		return interactiveErr(err)
+	}
+	stderr, err := s.StderrPipe()
+	if err != nil {
+		s.Close()
+		return interactiveErr(err)
+	}
+	if err := s.Start(c.command); err != nil {
+		s.Close()
+		return interactiveErr(fmt.Errorf("failed to start command: %w", err))
+	}
+
+	closer := func() error {
+		defer s.Close()
+		return s.Wait()
+	}
+	return stdin, stdout, stderr, closer, nil
+}
+
+// interactiveErr returns err with empty streams, so that callers can still drain
+// stderr on failure.
+func interactiveErr(err error) (io.WriteCloser, io.Reader, io.Reader, remote.Closer, error) {
+	return nil, bytes.NewReader(nil), bytes.NewReader(nil), nil, err
+}
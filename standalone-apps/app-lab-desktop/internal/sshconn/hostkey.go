@@ -0,0 +1,45 @@
+package sshconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var errHostKeyReceived = errors.New("host key received")
+
+// HostKey performs the SSH handshake with the server at addr up to the key exchange
+// and returns its host key, without authenticating.
+func HostKey(ctx context.Context, addr string) (ssh.PublicKey, error) {
+	d := net.Dialer{Timeout: dialTimeout}
+	tcpConn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", addr, err)
+	}
+	defer tcpConn.Close()
+	// The handshake is not bound to ctx.
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(dialTimeout)
+	}
+	_ = tcpConn.SetDeadline(deadline)
+
+	var key ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: DefaultUser,
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errHostKeyReceived
+		},
+		Timeout: dialTimeout,
+	}
+	_, _, _, err = ssh.NewClientConn(tcpConn, addr, config)
+	if key == nil {
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", addr, err)
+	}
+	return key, nil
+}
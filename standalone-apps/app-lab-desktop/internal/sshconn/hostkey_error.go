@@ -0,0 +1,16 @@
+package sshconn
+
+import "fmt"
+
+// HostKeyMismatchError is returned when the host key of a server does not match the
+// pinned one: the board has been re-flashed, swapped, or someone is impersonating it.
+type HostKeyMismatchError struct {
+	Identity string
+	Address  string
+	Expected string
+	Got      string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key of %s changed: expected %s, got %s", e.Address, e.Expected, e.Got)
+}
@@ -0,0 +1,290 @@
+// Package sshconn implements remote.RemoteConn on top of golang.org/x/crypto/ssh,
+// for the connections that need more control than the arduino-app-cli one gives,
+// such as a custom port.
+package sshconn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	DefaultPort = 22
+	DefaultUser = "arduino"
+	dialTimeout = 10 * time.Second
+)
+
+type Conn struct {
+	client *ssh.Client
+
+	forwardsMu sync.Mutex
+	forwards   map[int]net.Listener
+	reverses   map[int]net.Listener
+}
+
+var _ remote.RemoteConn = (*Conn)(nil)
+
+// Dial connects to the SSH server at addr ("host:port").
+func Dial(ctx context.Context, addr string, config *ssh.ClientConfig) (*Conn, error) {
+	if config.Timeout == 0 {
+		config.Timeout = dialTimeout
+	}
+	d := net.Dialer{Timeout: config.Timeout}
+	tcpConn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", addr, err)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(tcpConn, addr, config)
+	if err != nil {
+		tcpConn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", addr, err)
+	}
+	return New(ssh.NewClient(c, chans, reqs)), nil
+}
+
+func New(client *ssh.Client) *Conn {
+	return &Conn{
+		client:   client,
+		forwards: make(map[int]net.Listener),
+		reverses: make(map[int]net.Listener),
+	}
+}
+
+// Client returns the underlying SSH client.
+func (c *Conn) Client() *ssh.Client {
+	return c.client
+}
+
+func (c *Conn) Close() error {
+	c.closeForwards()
+	return c.client.Close()
+}
+
+func (c *Conn) List(p string) ([]remote.FileInfo, error) {
+	out, err := c.GetCmd("find", p, "-mindepth", "1", "-maxdepth", "1", "-printf", `%y\t%f\n`).Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", p, err)
+	}
+
+	var files []remote.FileInfo
+	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+		kind, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		files = append(files, remote.FileInfo{Name: name, IsDir: kind == "d"})
+	}
+	return files, nil
+}
+
+func (c *Conn) MkDirAll(p string) error {
+	if err := c.GetCmd("mkdir", "-p", "--", p).Run(context.Background()); err != nil {
+		return fmt.Errorf("failed to create %s: %w", p, err)
+	}
+	return nil
+}
+
+func (c *Conn) WriteFile(data io.Reader, p string) error {
+	s, err := c.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open session: %w", err)
+	}
+	defer s.Close()
+
+	var stderr bytes.Buffer
+	s.Stdin = data
+	s.Stderr = &stderr
+	if err := s.Run("cat > " + Quote(p)); err != nil {
+		return fmt.Errorf("failed to write %s: %w: %s", p, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (c *Conn) ReadFile(p string) (io.ReadCloser, error) {
+	out, err := c.GetCmd("cat", "--", p).Output(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	return io.NopCloser(bytes.NewReader(out)), nil
+}
+
+func (c *Conn) Remove(p string) error {
+	if err := c.GetCmd("rm", "-rf", "--", p).Run(context.Background()); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", p, err)
+	}
+	return nil
+}
+
+func (c *Conn) Stats(p string) (remote.FileInfo, error) {
+	out, err := c.GetCmd("stat", "-c", "%F", "--", p).Output(context.Background())
+	if err != nil {
+		return remote.FileInfo{}, fmt.Errorf("failed to stat %s: %w", p, err)
+	}
+	return remote.FileInfo{
+		Name:  path.Base(p),
+		IsDir: strings.TrimSpace(string(out)) == "directory",
+	}, nil
+}
+
+func (c *Conn) GetCmd(cmd string, args ...string) remote.Cmder {
+	words := make([]string, 0, len(args)+1)
+	words = append(words, Quote(cmd))
+	for _, a := range args {
+		words = append(words, Quote(a))
+	}
+	return &cmder{client: c.client, command: strings.Join(words, " ")}
+}
+
+// Forward listens on localhost:localPort and forwards every connection to
+// localhost:remotePort on the board.
+func (c *Conn) Forward(ctx context.Context, localPort, remotePort int) error {
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)))
+	if err != nil {
+		// The port in use error differs between the platforms, the caller retries
+		// with another port on any failure.
+		return fmt.Errorf("failed to listen on port %d: %w: %w", localPort, remote.ErrPortAvailable, err)
+	}
+
+	c.forwardsMu.Lock()
+	c.forwards[localPort] = l
+	c.forwardsMu.Unlock()
+
+	go func() {
+		for {
+			local, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go c.forward(local, remotePort)
+		}
+	}()
+	return nil
+}
+
+func (c *Conn) forward(local net.Conn, remotePort int) {
+	defer local.Close()
+
+	board, err := c.client.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(remotePort)))
+	if err != nil {
+		return
+	}
+	defer board.Close()
+
+	relay(local, board)
+}
+
+// relay copies data both ways until one of the sides is closed.
+func relay(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// ForwardKill stops the forwarding listening on localPort.
+func (c *Conn) ForwardKill(_ context.Context, localPort int) error {
+	c.forwardsMu.Lock()
+	l, ok := c.forwards[localPort]
+	delete(c.forwards, localPort)
+	c.forwardsMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no forward on port %d", localPort)
+	}
+	return l.Close()
+}
+
+func (c *Conn) ForwardKillAll(context.Context) error {
+	c.closeForwards()
+	return nil
+}
+
+// ReverseForward asks the board to listen on localhost:boardPort and forwards every
+// connection to localhost:localPort on the host.
+func (c *Conn) ReverseForward(_ context.Context, boardPort, localPort int) error {
+	l, err := c.client.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(boardPort)))
+	if err != nil {
+		return fmt.Errorf("failed to listen on board port %d: %w", boardPort, err)
+	}
+
+	c.forwardsMu.Lock()
+	c.reverses[boardPort] = l
+	c.forwardsMu.Unlock()
+
+	go func() {
+		for {
+			board, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go reverseForward(board, localPort)
+		}
+	}()
+	return nil
+}
+
+func reverseForward(board net.Conn, localPort int) {
+	defer board.Close()
+
+	local, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)))
+	if err != nil {
+		return
+	}
+	defer local.Close()
+
+	relay(board, local)
+}
+
+// ReverseKill stops the reverse forwarding listening on boardPort.
+func (c *Conn) ReverseKill(_ context.Context, boardPort int) error {
+	c.forwardsMu.Lock()
+	l, ok := c.reverses[boardPort]
+	delete(c.reverses, boardPort)
+	c.forwardsMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no reverse forward on board port %d", boardPort)
+	}
+	return l.Close()
+}
+
+func (c *Conn) closeForwards() {
+	c.forwardsMu.Lock()
+	defer c.forwardsMu.Unlock()
+	for port, l := range c.forwards {
+		_ = l.Close()
+		delete(c.forwards, port)
+	}
+	for port, l := range c.reverses {
+		_ = l.Close()
+		delete(c.reverses, port)
+	}
+}
+
+// Quote quotes s for a POSIX shell.
+func Quote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,24 @@
+package sshconn
+
+import (
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"", "''"},
+		{"/home/arduino/ArduinoApps", "/home/arduino/ArduinoApps"},
+		{"my app", "'my app'"},
+		{"it's", `'it'\''s'`},
+		{"$(reboot)", "'$(reboot)'"},
+		{`%y\t%f\n`, `'%y\t%f\n'`},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.in); got != tt.expected {
+			t.Errorf("Quote(%q) = %s, expected %s", tt.in, got, tt.expected)
+		}
+	}
+}
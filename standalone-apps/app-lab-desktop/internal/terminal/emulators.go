@@ -0,0 +1,305 @@
+package terminal
+
+import (
+	"app-lab-desktop/internal/config"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/arduino/go-paths-helper"
+)
+
+const (
+	settingsFile = "terminal.json"
+	// Placeholders of the terminal command template: {script} is a script running the
+	// command and waiting for Enter before exiting, {command} is the command itself.
+	scriptPlaceholder  = "{script}"
+	commandPlaceholder = "{command}"
+	// launchCheckDelay is how long a terminal has to fail before being considered open,
+	// since most of them run until their window is closed.
+	launchCheckDelay = 2 * time.Second
+	// scriptCleanupDelay leaves the time to the terminals handing over to a server,
+	// like gnome-terminal, to read the script before it is removed.
+	scriptCleanupDelay = time.Minute
+)
+
+// Settings are the user settings of the external terminal.
+type Settings struct {
+	// Command is the template of the command opening the terminal, e.g.
+	// "kitty sh {script}". When empty, the terminal is detected.
+	Command string `json:"command"`
+}
+
+// Emulator is a terminal emulator known by the app, with the template opening it.
+type Emulator struct {
+	Name      string `json:"name"`
+	Template  string `json:"template"`
+	Installed bool   `json:"installed"`
+}
+
+// linuxEmulators are tried in order when no command is configured.
+var linuxEmulators = []Emulator{
+	{Name: "gnome-terminal", Template: "gnome-terminal -- sh {script}"},
+	{Name: "xfce4-terminal", Template: "xfce4-terminal -e {script}"},
+	{Name: "konsole", Template: "konsole -e sh {script}"},
+	// mate-terminal with --disable-factory to avoid GTK widget management issues:
+	// error: "terminal_window_remove_screen: assertion 'gtk_widget_get_toplevel (GTK_WIDGET (screen)) == GTK_WIDGET (window)' failed\n"
+	{Name: "mate-terminal", Template: "mate-terminal --disable-factory -e {script}"},
+	{Name: "tilix", Template: "tilix -e {script}"},
+	{Name: "terminator", Template: "terminator -x sh {script}"},
+	{Name: "alacritty", Template: "alacritty -e sh {script}"},
+	{Name: "kitty", Template: "kitty sh {script}"},
+	{Name: "wezterm", Template: "wezterm start -- sh {script}"},
+	{Name: "foot", Template: "foot sh {script}"},
+	{Name: "xterm", Template: "xterm -e sh {script}"},
+	// x-terminal-emulator is a link to another terminal. It could fail if the underline terminal does not accept the '-e' argument.
+	{Name: "x-terminal-emulator", Template: "x-terminal-emulator -e {script}"},
+}
+
+func LoadSettings() (Settings, error) {
+	var s Settings
+	if err := config.Load(settingsFile, &s); err != nil {
+		return Settings{}, err
+	}
+	return s, nil
+}
+
+// SaveSettings validates and saves the settings, an empty command restores the detection.
+func SaveSettings(s Settings) error {
+	s.Command = strings.TrimSpace(s.Command)
+	if s.Command != "" {
+		if _, err := expandTemplate(s.Command, "script", nil); err != nil {
+			return err
+		}
+	}
+	return config.Save(settingsFile, s)
+}
+
+// ListEmulators returns the built-in emulators of the OS, telling which ones are on $PATH.
+func ListEmulators() []Emulator {
+	if runtime.GOOS != "linux" {
+		return []Emulator{}
+	}
+	emulators := make([]Emulator, len(linuxEmulators))
+	for i, e := range linuxEmulators {
+		emulators[i] = e
+		_, err := exec.LookPath(e.Name)
+		emulators[i].Installed = err == nil
+	}
+	return emulators
+}
+
+// TestTerminal opens the terminal with the given template, or the configured one
+// if empty, running a harmless command.
+func TestTerminal(template string) error {
+	if template == "" {
+		s, err := LoadSettings()
+		if err != nil {
+			return err
+		}
+		template = s.Command
+	}
+	args := []string{"echo", "Arduino App Lab terminal works"}
+	if template == "" {
+		return openTerminalWithArgs(args...)
+	}
+	return launchTemplate(template, args)
+}
+
+// configuredTemplate returns the terminal command set by the user, if any.
+func configuredTemplate() string {
+	s, err := LoadSettings()
+	if err != nil {
+		slog.Warn("failed to load terminal settings", "err", err)
+		return ""
+	}
+	return s.Command
+}
+
+// detectLinuxEmulator returns the first built-in emulator found on $PATH.
+func detectLinuxEmulator() (Emulator, bool) {
+	for _, e := range linuxEmulators {
+		if _, err := exec.LookPath(e.Name); err == nil {
+			return e, true
+		}
+	}
+	return Emulator{}, false
+}
+
+// launchTemplate opens the terminal of the template running args.
+func launchTemplate(template string, args []string) error {
+	usesScript := strings.Contains(template, scriptPlaceholder)
+	script := ""
+	if usesScript {
+		var err error
+		if script, err = writeScript(args); err != nil {
+			return err
+		}
+	}
+
+	cmdArgs, err := expandTemplate(template, script, args)
+	if err != nil {
+		removeScript(script, 0)
+		return err
+	}
+
+	cmd, err := paths.NewProcess(nil, cmdArgs...)
+	if err != nil {
+		removeScript(script, 0)
+		return fmt.Errorf("failed to open terminal %s: %w", cmdArgs[0], err)
+	}
+	var output bytes.Buffer
+	cmd.RedirectStdoutTo(&output)
+	cmd.RedirectStderrTo(&output)
+	slog.Info("Opening terminal", "cmd", cmd.GetArgs())
+	if err := cmd.Start(); err != nil {
+		removeScript(script, 0)
+		return fmt.Errorf("failed to open terminal %s: %w", cmdArgs[0], err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		removeScript(script, scriptCleanupDelay)
+		exited <- err
+	}()
+	select {
+	case err := <-exited:
+		if err != nil {
+			slog.Error("failed to start terminal", "err", err, "cmd", cmd.GetArgs(), "output", output.String())
+			return fmt.Errorf("terminal %s failed: %w", cmdArgs[0], err)
+		}
+	case <-time.After(launchCheckDelay):
+	}
+	return nil
+}
+
+// expandTemplate splits the template into the command arguments, replacing the
+// placeholders. An unquoted {command} argument is replaced by all the arguments of
+// the command, otherwise it is replaced by the command line.
+func expandTemplate(template string, script string, args []string) ([]string, error) {
+	words, err := splitWords(template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid terminal command: %w", err)
+	}
+	if len(words) == 0 {
+		return nil, errors.New("invalid terminal command: empty")
+	}
+	if !strings.Contains(template, scriptPlaceholder) && !strings.Contains(template, commandPlaceholder) {
+		return nil, fmt.Errorf("invalid terminal command: it must contain %s or %s", scriptPlaceholder, commandPlaceholder)
+	}
+
+	commandLine := strings.Join(args, " ")
+	var expanded []string
+	for _, w := range words {
+		if w.text == commandPlaceholder && !w.quoted {
+			expanded = append(expanded, args...)
+			continue
+		}
+		text := strings.ReplaceAll(w.text, scriptPlaceholder, script)
+		text = strings.ReplaceAll(text, commandPlaceholder, commandLine)
+		expanded = append(expanded, text)
+	}
+	return expanded, nil
+}
+
+type shellWord struct {
+	text string
+	// quoted is set if any part of the word is quoted or escaped.
+	quoted bool
+}
+
+// splitWords splits s into words like a POSIX shell, honoring the single and double
+// quotes and the backslash escapes, without any expansion.
+func splitWords(s string) ([]shellWord, error) {
+	var words []shellWord
+	var word strings.Builder
+	inWord, quoted := false, false
+	var quote rune
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]):
+				i++
+				word.WriteRune(runes[i])
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord, quoted = true, true
+		case r == '\\':
+			if i+1 == len(runes) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord, quoted = true, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, shellWord{text: word.String(), quoted: quoted})
+				word.Reset()
+				inWord, quoted = false, false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, shellWord{text: word.String(), quoted: quoted})
+	}
+	return words, nil
+}
+
+// writeScript writes a temporary script running args, that keeps the terminal open
+// after the command execution.
+func writeScript(args []string) (string, error) {
+	pattern, content := "run_in_terminal_*.sh", fmt.Sprintf("#!/bin/sh\n%s\necho \"Press Enter to close...\"\nread dummy\n", strings.Join(args, " "))
+	if runtime.GOOS == "windows" {
+		pattern, content = "run_in_terminal_*.cmd", fmt.Sprintf("@echo off\r\n%s\r\npause\r\n", strings.Join(args, " "))
+	}
+
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		slog.Error("failed to create file", "err", err)
+		return "", errors.New("fail to create temporary script")
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		slog.Error("failed to write file", "err", err, "path", f.Name())
+		return "", errors.New("fail to create temporary script")
+	}
+	if err := f.Chmod(0700); err != nil && runtime.GOOS != "windows" {
+		return "", fmt.Errorf("fail to create temporary script: %w", err)
+	}
+	return f.Name(), nil
+}
+
+func removeScript(script string, delay time.Duration) {
+	if script == "" {
+		return
+	}
+	time.AfterFunc(delay, func() { _ = os.Remove(script) })
+}
@@ -0,0 +1,68 @@
+package terminal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected []string
+		err      bool
+	}{
+		{in: "kitty sh {script}", expected: []string{"kitty", "sh", "{script}"}},
+		{in: "  wezterm   start -- {command} ", expected: []string{"wezterm", "start", "--", "{command}"}},
+		{in: `tilix -e 'sh {script}'`, expected: []string{"tilix", "-e", "sh {script}"}},
+		{in: `xterm -T "App \"Lab\"" -e {script}`, expected: []string{"xterm", "-T", `App "Lab"`, "-e", "{script}"}},
+		{in: `foot --title=App\ Lab sh {script}`, expected: []string{"foot", "--title=App Lab", "sh", "{script}"}},
+		{in: `a ''`, expected: []string{"a", ""}},
+		{in: `kitty 'sh {script}`, err: true},
+		{in: `kitty \`, err: true},
+	}
+
+	for _, tt := range tests {
+		words, err := splitWords(tt.in)
+		var got []string
+		for _, w := range words {
+			got = append(got, w.text)
+		}
+		if tt.err {
+			if err == nil {
+				t.Errorf("splitWords(%q): expected an error", tt.in)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tt.expected) {
+			t.Errorf("splitWords(%q) = %q, %v, expected %q", tt.in, got, err, tt.expected)
+		}
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	args := []string{"ssh", "arduino@192.168.1.10"}
+	tests := []struct {
+		template string
+		expected []string
+		err      bool
+	}{
+		{template: "kitty sh {script}", expected: []string{"kitty", "sh", "/tmp/run.sh"}},
+		{template: "wezterm start -- {command}", expected: []string{"wezterm", "start", "--", "ssh", "arduino@192.168.1.10"}},
+		{template: "tilix -e '{command}'", expected: []string{"tilix", "-e", "ssh arduino@192.168.1.10"}},
+		{template: "kitty", err: true},
+		{template: "", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := expandTemplate(tt.template, "/tmp/run.sh", args)
+		if tt.err {
+			if err == nil {
+				t.Errorf("expandTemplate(%q): expected an error", tt.template)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tt.expected) {
+			t.Errorf("expandTemplate(%q) = %q, %v, expected %q", tt.template, got, err, tt.expected)
+		}
+	}
+}
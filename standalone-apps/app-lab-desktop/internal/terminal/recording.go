@@ -0,0 +1,384 @@
+package terminal
+
+import (
+	"app-lab-desktop/internal/config"
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	recordingsDir = "recordings"
+	recordingExt  = ".cast"
+	// maxReplayPause caps the idle time between two replayed events.
+	maxReplayPause = 2 * time.Second
+)
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recording describes a recorded shell session.
+type Recording struct {
+	Name      string    `json:"name"`
+	Title     string    `json:"title"`
+	StartedAt time.Time `json:"startedAt"`
+	Duration  float64   `json:"duration"`
+	Size      int64     `json:"size"`
+}
+
+// recorder writes a shell session in the asciicast v2 format: a header line, then
+// one [elapsed seconds, type, data] line per output ("o"), input ("i") or
+// resize ("r") event.
+type recorder struct {
+	mu      sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	start   time.Time
+	pending map[string][]byte
+}
+
+func newRecorder(name string, title string, cols, rows int) (*recorder, error) {
+	dir, err := recordingsPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	start := time.Now()
+	r := &recorder{f: f, w: bufio.NewWriter(f), start: start, pending: make(map[string][]byte)}
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.w.Write(append(header, '\n'))
+	return r, nil
+}
+
+// event records data, keeping back the bytes of an incomplete UTF-8 sequence until
+// the next event of the same type, since asciicast data are strings.
+func (r *recorder) event(kind string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return
+	}
+
+	var complete []byte
+	complete, r.pending[kind] = splitUTF8(append(r.pending[kind], data...))
+	if len(complete) == 0 {
+		return
+	}
+	r.write(kind, string(complete))
+}
+
+func (r *recorder) resize(cols, rows int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return
+	}
+	r.write("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+func (r *recorder) write(kind string, data string) {
+	line, err := json.Marshal([]any{time.Since(r.start).Seconds(), kind, data})
+	if err != nil {
+		return
+	}
+	r.w.Write(append(line, '\n'))
+}
+
+func (r *recorder) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := errors.Join(r.w.Flush(), r.f.Close())
+	r.f = nil
+	return err
+}
+
+// splitUTF8 splits data before a trailing incomplete UTF-8 sequence.
+func splitUTF8(data []byte) (complete, rest []byte) {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(data[i]) {
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			return data[:i], slices.Clone(data[i:])
+		}
+		break
+	}
+	return data, nil
+}
+
+func recordingsPath() (string, error) {
+	dir, err := config.Path(recordingsDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create recordings dir: %w", err)
+	}
+	return dir, nil
+}
+
+// recordingPath returns the path of the named recording, refusing names that
+// would escape the recordings dir.
+func recordingPath(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || !strings.HasSuffix(name, recordingExt) {
+		return "", fmt.Errorf("invalid recording name %q", name)
+	}
+	dir, err := recordingsPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// ListRecordings returns the saved recordings, the most recent first.
+func ListRecordings() ([]Recording, error) {
+	dir, err := recordingsPath()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+
+	recordings := []Recording{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), recordingExt) {
+			continue
+		}
+		rec, err := readRecordingInfo(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		recordings = append(recordings, rec)
+	}
+	slices.SortFunc(recordings, func(a, b Recording) int { return b.StartedAt.Compare(a.StartedAt) })
+	return recordings, nil
+}
+
+func readRecordingInfo(path string) (Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Recording{}, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return Recording{}, err
+	}
+
+	header, events, err := parseCast(f)
+	if err != nil {
+		return Recording{}, err
+	}
+	rec := Recording{
+		Name:      filepath.Base(path),
+		Title:     header.Title,
+		StartedAt: time.Unix(header.Timestamp, 0),
+		Size:      st.Size(),
+	}
+	if len(events) > 0 {
+		rec.Duration = events[len(events)-1].Time
+	}
+	return rec, nil
+}
+
+// castEvent is an event line of an asciicast v2 file.
+type castEvent struct {
+	Time float64
+	Type string
+	Data string
+}
+
+func parseCast(r io.Reader) (castHeader, []castEvent, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var header castHeader
+	if !s.Scan() {
+		return header, nil, errors.New("empty recording")
+	}
+	if err := json.Unmarshal(s.Bytes(), &header); err != nil {
+		return header, nil, fmt.Errorf("invalid recording header: %w", err)
+	}
+	if header.Version != 2 {
+		return header, nil, fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+
+	var events []castEvent
+	for s.Scan() {
+		var fields []json.RawMessage
+		if err := json.Unmarshal(s.Bytes(), &fields); err != nil || len(fields) != 3 {
+			// A recording interrupted by a crash may end with a truncated line.
+			continue
+		}
+		var e castEvent
+		if json.Unmarshal(fields[0], &e.Time) != nil || json.Unmarshal(fields[1], &e.Type) != nil || json.Unmarshal(fields[2], &e.Data) != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	return header, events, s.Err()
+}
+
+// ExportRecording copies the named recording to dest.
+func ExportRecording(name string, dest string) error {
+	src, err := recordingPath(name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read recording %s: %w", name, err)
+	}
+	if err := os.WriteFile(dest, data, 0600); err != nil {
+		return fmt.Errorf("failed to export recording %s: %w", name, err)
+	}
+	return nil
+}
+
+func DeleteRecording(name string) error {
+	p, err := recordingPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		return fmt.Errorf("failed to delete recording %s: %w", name, err)
+	}
+	return nil
+}
+
+// StartRecording records the shell, from now on, to a new recording and returns its name.
+func (m *Manager) StartRecording(id string) (string, error) {
+	s, err := m.get(id)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s.recorder != nil {
+		return "", fmt.Errorf("shell %s is already recorded", id)
+	}
+	name := time.Now().Format("20060102-150405") + "-" + id + recordingExt
+	r, err := newRecorder(name, s.title, s.Cols, s.Rows)
+	if err != nil {
+		return "", err
+	}
+	s.recorder = r
+	s.Recording = name
+	return name, nil
+}
+
+// StopRecording stops the recording of the shell and saves it.
+func (m *Manager) StopRecording(id string) error {
+	s, err := m.get(id)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	r := s.recorder
+	s.recorder = nil
+	s.Recording = ""
+	m.mu.Unlock()
+
+	if r == nil {
+		return fmt.Errorf("shell %s is not recorded", id)
+	}
+	return r.close()
+}
+
+// Replay streams the named recording as the output of a read-only shell, with the
+// same events as a live one. Long pauses are shortened and speed scales the timing.
+func (m *Manager) Replay(name string, speed float64) (ShellInfo, error) {
+	p, err := recordingPath(name)
+	if err != nil {
+		return ShellInfo{}, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return ShellInfo{}, fmt.Errorf("failed to open recording %s: %w", name, err)
+	}
+	defer f.Close()
+	header, events, err := parseCast(f)
+	if err != nil {
+		return ShellInfo{}, err
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+
+	id, err := newShellID()
+	if err != nil {
+		return ShellInfo{}, err
+	}
+	id = "replay-" + id
+	info := ShellInfo{ID: id, Cols: header.Width, Rows: header.Height, Recording: name}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	m.replays[id] = cancel
+	m.mu.Unlock()
+
+	go func() {
+		defer func() {
+			m.mu.Lock()
+			delete(m.replays, id)
+			m.mu.Unlock()
+			cancel()
+			m.onEvent(Event{Type: ShellExited, ID: id})
+		}()
+
+		var last float64
+		for _, e := range events {
+			pause := min(time.Duration((e.Time-last)/speed*float64(time.Second)), maxReplayPause)
+			last = e.Time
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pause):
+			}
+			// Only the output is shown, the input is echoed back by the terminal anyway.
+			if e.Type == "o" {
+				m.onEvent(Event{Type: ShellOutput, ID: id, Data: base64.StdEncoding.EncodeToString([]byte(e.Data))})
+			}
+		}
+	}()
+	return info, nil
+}
@@ -0,0 +1,83 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSplitUTF8(t *testing.T) {
+	euro := []byte("€") // 3 bytes
+	tests := []struct {
+		name     string
+		data     []byte
+		complete []byte
+		rest     []byte
+	}{
+		{name: "ascii", data: []byte("ls\r\n"), complete: []byte("ls\r\n")},
+		{name: "complete rune", data: append([]byte("a"), euro...), complete: append([]byte("a"), euro...)},
+		{name: "split rune", data: append([]byte("a"), euro[:2]...), complete: []byte("a"), rest: euro[:2]},
+		{name: "only a rune start", data: euro[:1], complete: []byte{}, rest: euro[:1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			complete, rest := splitUTF8(tt.data)
+			if !slices.Equal(complete, tt.complete) || !slices.Equal(rest, tt.rest) {
+				t.Errorf("expected %q and %q, got %q and %q", tt.complete, tt.rest, complete, rest)
+			}
+		})
+	}
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	t.Setenv("ARDUINO_APP_LAB_CONFIG_DIR", t.TempDir())
+
+	r, err := newRecorder("session.cast", "uno-q", 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	euro := []byte("€")
+	r.event("i", []byte("echo "))
+	r.event("o", append([]byte("price "), euro[:1]...))
+	r.event("o", euro[1:])
+	r.resize(120, 40)
+	if err := r.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	recordings, err := ListRecordings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recordings) != 1 || recordings[0].Name != "session.cast" || recordings[0].Title != "uno-q" {
+		t.Fatalf("unexpected recordings %+v", recordings)
+	}
+
+	p, _ := recordingPath("session.cast")
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	header, events, err := parseCast(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Width != 80 || header.Height != 24 {
+		t.Errorf("unexpected header %+v", header)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Type+":"+e.Data)
+	}
+	expected := []string{"i:echo ", "o:price ", "o:€", "r:120x40"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected events %q, got %q", expected, got)
+	}
+
+	if _, err := recordingPath(filepath.Join("..", "session.cast")); err == nil {
+		t.Errorf("expected an error for a path outside the recordings dir")
+	}
+}
@@ -0,0 +1,348 @@
+package terminal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+)
+
+const (
+	DefaultCols = 80
+	DefaultRows = 24
+	// closeTimeout bounds the wait for the remote shell to exit once hung up.
+	closeTimeout = 5 * time.Second
+)
+
+type EventType string
+
+const (
+	ShellOutput EventType = "output"
+	ShellExited EventType = "exit"
+)
+
+// Event reports the output of a shell, base64 encoded since it is raw terminal
+// data, or its end.
+type Event struct {
+	Type    EventType `json:"type"`
+	ID      string    `json:"id"`
+	BoardID string    `json:"boardId"`
+	Data    string    `json:"data,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// ShellInfo describes an open shell.
+type ShellInfo struct {
+	ID      string `json:"id"`
+	BoardID string `json:"boardId"`
+	Cols    int    `json:"cols"`
+	Rows    int    `json:"rows"`
+	// Recording is the name of the recording in progress, or of the replayed one.
+	Recording string `json:"recording,omitempty"`
+}
+
+// Shell is an interactive shell on the board. The connections do not allocate a
+// PTY, so the shell runs under script(1), that allocates one on the board: its path
+// is saved in ttyFile so that the shell can be resized from another command.
+type Shell struct {
+	ShellInfo
+	conn     remote.RemoteConn
+	stdin    io.WriteCloser
+	closer   remote.Closer
+	title    string
+	ttyFile  string
+	exited   chan struct{}
+	recorder *recorder
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Manager keeps the shells open on the boards, several per board.
+type Manager struct {
+	onEvent func(Event)
+
+	mu      sync.Mutex
+	shells  map[string]*Shell
+	replays map[string]context.CancelFunc
+}
+
+func NewManager(onEvent func(Event)) *Manager {
+	return &Manager{
+		onEvent: onEvent,
+		shells:  make(map[string]*Shell),
+		replays: make(map[string]context.CancelFunc),
+	}
+}
+
+// Open starts a login shell on the board. The shell is closed when ctx, usually the
+// one of the board session, is done.
+func (m *Manager) Open(ctx context.Context, boardID string, title string, conn remote.RemoteConn, cols, rows int) (ShellInfo, error) {
+	if cols <= 0 || rows <= 0 {
+		cols, rows = DefaultCols, DefaultRows
+	}
+	id, err := newShellID()
+	if err != nil {
+		return ShellInfo{}, err
+	}
+
+	stdin, stdout, stderr, closer, err := conn.GetCmd("sh").Interactive()
+	if err != nil {
+		return ShellInfo{}, fmt.Errorf("failed to start shell: %w", err)
+	}
+
+	s := &Shell{
+		ShellInfo: ShellInfo{ID: id, BoardID: boardID, Cols: cols, Rows: rows},
+		conn:      conn,
+		stdin:     stdin,
+		closer:    closer,
+		title:     title,
+		ttyFile:   "/tmp/app-lab-terminal-" + id + ".tty",
+		exited:    make(chan struct{}),
+	}
+	// The bootstrap goes through the standard input, instead of the command arguments,
+	// since the connections do not quote them the same way.
+	if _, err := io.WriteString(stdin, bootstrapScript(s.ttyFile, cols, rows)); err != nil {
+		_ = stdin.Close()
+		return ShellInfo{}, errors.Join(fmt.Errorf("failed to start shell: %w", err), closer())
+	}
+
+	m.mu.Lock()
+	m.shells[id] = s
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, r := range []io.Reader{stdout, stderr} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.pipe(s, r)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		m.exited(s, s.wait())
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = m.Close(id)
+		case <-s.exited:
+		}
+	}()
+
+	return s.ShellInfo, nil
+}
+
+// Write sends the user input to the shell.
+func (m *Manager) Write(id string, data []byte) error {
+	s, err := m.get(id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.stdin.Write(data); err != nil {
+		return fmt.Errorf("failed to write to shell %s: %w", id, err)
+	}
+	if r := m.recorderOf(s); r != nil {
+		r.event("i", data)
+	}
+	return nil
+}
+
+// Resize sets the size of the shell terminal, the kernel notifies the running
+// program with SIGWINCH.
+func (m *Manager) Resize(id string, cols, rows int) error {
+	if cols <= 0 || rows <= 0 {
+		return fmt.Errorf("invalid terminal size %dx%d", cols, rows)
+	}
+	s, err := m.get(id)
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf("stty -F \"$(cat %s)\" rows %d cols %d\n", s.ttyFile, rows, cols)
+	if err := runScript(s.conn, script); err != nil {
+		return fmt.Errorf("failed to resize shell %s: %w", id, err)
+	}
+
+	m.mu.Lock()
+	s.Cols, s.Rows = cols, rows
+	r := s.recorder
+	m.mu.Unlock()
+	if r != nil {
+		r.resize(cols, rows)
+	}
+	return nil
+}
+
+// Close hangs up the shell and the programs it started.
+func (m *Manager) Close(id string) error {
+	m.mu.Lock()
+	cancel, replay := m.replays[id]
+	m.mu.Unlock()
+	if replay {
+		cancel()
+		return nil
+	}
+
+	s, err := m.get(id)
+	if err != nil {
+		return err
+	}
+	if !s.markClosed() {
+		return nil
+	}
+
+	_ = s.stdin.Close()
+	script := fmt.Sprintf("t=$(cat %[1]s) && rm -f %[1]s && pkill -HUP -t \"${t#/dev/}\"\n", s.ttyFile)
+	if err := runScript(s.conn, script); err != nil {
+		slog.Warn("failed to hang up shell", "id", id, "err", err)
+	}
+	return nil
+}
+
+// List returns the shells open on the board, or on every board if boardID is empty.
+func (m *Manager) List(boardID string) []ShellInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	infos := make([]ShellInfo, 0, len(m.shells))
+	for _, s := range m.shells {
+		if boardID == "" || s.BoardID == boardID {
+			infos = append(infos, s.ShellInfo)
+		}
+	}
+	slices.SortFunc(infos, func(a, b ShellInfo) int { return strings.Compare(a.ID, b.ID) })
+	return infos
+}
+
+func (m *Manager) get(id string) (*Shell, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.shells[id]
+	if !ok {
+		return nil, fmt.Errorf("shell %s not found", id)
+	}
+	return s, nil
+}
+
+func (m *Manager) recorderOf(s *Shell) *recorder {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return s.recorder
+}
+
+func (m *Manager) pipe(s *Shell, r io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if rec := m.recorderOf(s); rec != nil {
+				rec.event("o", buf[:n])
+			}
+			m.onEvent(Event{
+				Type:    ShellOutput,
+				ID:      s.ID,
+				BoardID: s.BoardID,
+				Data:    base64.StdEncoding.EncodeToString(buf[:n]),
+			})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (m *Manager) exited(s *Shell, err error) {
+	m.mu.Lock()
+	delete(m.shells, s.ID)
+	r := s.recorder
+	s.recorder = nil
+	m.mu.Unlock()
+	s.markClosed()
+	if r != nil {
+		if err := r.close(); err != nil {
+			slog.Warn("failed to save shell recording", "id", s.ID, "err", err)
+		}
+	}
+	close(s.exited)
+
+	e := Event{Type: ShellExited, ID: s.ID, BoardID: s.BoardID}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	m.onEvent(e)
+}
+
+// wait waits for the remote shell to exit, without blocking forever if the
+// connection is gone.
+func (s *Shell) wait() error {
+	done := make(chan error, 1)
+	go func() { done <- s.closer() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(closeTimeout):
+		return errors.New("shell did not exit")
+	}
+}
+
+// markClosed returns false if the shell was already closed.
+func (s *Shell) markClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.closed = true
+	return true
+}
+
+// bootstrapScript replaces the plain shell with a login shell running on a PTY
+// allocated by script(1).
+func bootstrapScript(ttyFile string, cols, rows int) string {
+	inner := fmt.Sprintf(`tty > %s; stty rows %d cols %d; export TERM=xterm-256color; exec "${SHELL:-/bin/sh}" -l`, ttyFile, rows, cols)
+	return fmt.Sprintf("exec script -qfc '%s' /dev/null\n", inner)
+}
+
+// runScript runs a shell script on the board, passed through the standard input.
+func runScript(conn remote.RemoteConn, script string) error {
+	stdin, stdout, stderr, closer, err := conn.GetCmd("sh").Interactive()
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(stdin, script); err != nil {
+		_ = stdin.Close()
+		return errors.Join(err, closer())
+	}
+	_ = stdin.Close()
+	// Both streams are read at once, a script filling the one not read would block.
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		_, _ = io.Copy(io.Discard, stdout)
+	}()
+	errOut, _ := io.ReadAll(stderr)
+	<-drained
+	if err := closer(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(errOut)))
+	}
+	return nil
+}
+
+func newShellID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate shell id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,199 @@
+package terminal
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+)
+
+// fakeConn runs the first command as the shell, and the next ones as the scripts
+// resizing or hanging it up.
+type fakeConn struct {
+	remote.RemoteConn
+	failWrite bool
+
+	mu      sync.Mutex
+	started bool
+	shell   *fakeShell
+	scripts []string
+}
+
+func (c *fakeConn) GetCmd(string, ...string) remote.Cmder {
+	return &fakeCmder{conn: c}
+}
+
+type fakeCmder struct {
+	remote.Cmder
+	conn *fakeConn
+}
+
+func (c *fakeCmder) Interactive() (io.WriteCloser, io.Reader, io.Reader, remote.Closer, error) {
+	conn := c.conn
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if !conn.started {
+		conn.started = true
+		stdout, out := io.Pipe()
+		conn.shell = &fakeShell{fail: conn.failWrite, out: out, hungUp: make(chan struct{})}
+		closer := func() error {
+			conn.shell.closerCalls.Add(1)
+			<-conn.shell.hungUp
+			return nil
+		}
+		if conn.failWrite {
+			closer = func() error {
+				conn.shell.closerCalls.Add(1)
+				return nil
+			}
+		}
+		return conn.shell, stdout, strings.NewReader(""), closer, nil
+	}
+
+	script := &strings.Builder{}
+	closer := func() error {
+		conn.mu.Lock()
+		defer conn.mu.Unlock()
+		conn.scripts = append(conn.scripts, script.String())
+		if strings.Contains(script.String(), "pkill -HUP") {
+			conn.shell.hangUp()
+		}
+		return nil
+	}
+	return nopWriteCloser{script}, strings.NewReader(""), strings.NewReader(""), closer, nil
+}
+
+// fakeShell records the input of the shell and exits when hung up.
+type fakeShell struct {
+	fail        bool
+	out         *io.PipeWriter
+	closerCalls atomic.Int32
+
+	mu     sync.Mutex
+	input  strings.Builder
+	once   sync.Once
+	hungUp chan struct{}
+}
+
+func (s *fakeShell) Write(p []byte) (int, error) {
+	if s.fail {
+		return 0, errors.New("connection lost")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.input.Write(p)
+}
+
+func (s *fakeShell) Close() error { return nil }
+
+func (s *fakeShell) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.input.String()
+}
+
+func (s *fakeShell) hangUp() {
+	s.once.Do(func() {
+		_ = s.out.Close()
+		close(s.hungUp)
+	})
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// waitEvent waits for the next event of the given type.
+func waitEvent(t *testing.T, events <-chan Event, expected EventType) Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == expected {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("expected a %s event", expected)
+		}
+	}
+}
+
+func TestShellOpenWriteClose(t *testing.T) {
+	events := make(chan Event, 16)
+	m := NewManager(func(e Event) { events <- e })
+	conn := &fakeConn{}
+
+	info, err := m.Open(context.Background(), "uno-q", "UNO Q", conn, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Cols != DefaultCols || info.Rows != DefaultRows {
+		t.Errorf("expected the default size, got %dx%d", info.Cols, info.Rows)
+	}
+	if input := conn.shell.String(); !strings.HasPrefix(input, "exec script ") {
+		t.Errorf("expected the bootstrap script, got %q", input)
+	}
+
+	if err := m.Write(info.ID, []byte("ls\n")); err != nil {
+		t.Fatal(err)
+	}
+	if input := conn.shell.String(); !strings.HasSuffix(input, "ls\n") {
+		t.Errorf("expected the user input to reach the shell, got %q", input)
+	}
+
+	go func() { _, _ = conn.shell.out.Write([]byte("hello")) }()
+	e := waitEvent(t, events, ShellOutput)
+	if data, _ := base64.StdEncoding.DecodeString(e.Data); string(data) != "hello" || e.ID != info.ID {
+		t.Errorf("expected the output of shell %s, got %q from %s", info.ID, data, e.ID)
+	}
+
+	if err := m.Close(info.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, ShellExited)
+	if shells := m.List(""); len(shells) != 0 {
+		t.Errorf("expected no shell left, got %+v", shells)
+	}
+	if err := m.Write(info.ID, []byte("ls\n")); err == nil {
+		t.Errorf("expected an error writing to a closed shell")
+	}
+}
+
+func TestShellClosedWithContext(t *testing.T) {
+	events := make(chan Event, 16)
+	m := NewManager(func(e Event) { events <- e })
+	conn := &fakeConn{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := m.Open(ctx, "uno-q", "UNO Q", conn, 80, 24); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	waitEvent(t, events, ShellExited)
+}
+
+func TestShellBootstrapFailure(t *testing.T) {
+	m := NewManager(func(Event) {})
+	conn := &fakeConn{failWrite: true}
+
+	if _, err := m.Open(context.Background(), "uno-q", "UNO Q", conn, 80, 24); err == nil {
+		t.Fatal("expected an error when the bootstrap cannot be written")
+	}
+	if n := conn.shell.closerCalls.Load(); n != 1 {
+		t.Errorf("expected the shell command to be closed once, got %d", n)
+	}
+	if shells := m.List(""); len(shells) != 0 {
+		t.Errorf("expected no shell left, got %+v", shells)
+	}
+}
@@ -41,6 +41,10 @@ func OpenTerminal(ctx context.Context, b *board.Board) error {
 }
 
 func openTerminalWithArgs(args ...string) error {
+	if template := configuredTemplate(); template != "" {
+		return launchTemplate(template, args)
+	}
+
 	switch runtime.GOOS {
 	case "windows":
 		cmdArgs := append([]string{"cmd.exe", "/C", "start", "cmd.exe", "/K"}, args...)
@@ -82,52 +86,15 @@ func openTerminalWithArgs(args ...string) error {
 	return nil
 }
 
-// openLinuxTerminal detects and opens the most common Linux terminals with the args
+// openLinuxTerminal opens the first built-in terminal found on $PATH with the args
 func openLinuxTerminal(args ...string) error {
-	commandStr := strings.Join(args, " ")
-
-	// Create a temporary wrapper script that keeps the terminal open after command execution
-	tmpScript := filepath.Join(os.TempDir(), "run_in_terminal_sh")
-	scriptContent := fmt.Sprintf("#!/bin/sh\n%s\necho \"Press Enter to close...\"\nread dummy\n", commandStr)
-	err := os.WriteFile(tmpScript, []byte(scriptContent), 0700)
-	if err != nil {
-		slog.Error("failed to write file", "err", err, "path", tmpScript)
-		return errors.New("fail to create temporary script")
-	}
-	defer os.Remove(tmpScript)
-
-	terminals := []struct {
-		cmd []string
-	}{
-		{[]string{"gnome-terminal", "--", "sh", tmpScript}},
-		{[]string{"xfce4-terminal", "-e", tmpScript}},
-		{[]string{"konsole", "-e", tmpScript}},
-		// mate-terminal with --disable-factory to avoid GTK widget management issues:
-		// error: "terminal_window_remove_screen: assertion 'gtk_widget_get_toplevel (GTK_WIDGET (screen)) == GTK_WIDGET (window)' failed\n"
-		{[]string{"mate-terminal", "--disable-factory", "-e", tmpScript}},
-		// x-terminal-emulator is a link to another terminal. It could fail if the underline terminal does not accept the '-e' argument.
-		{[]string{"x-terminal-emulator", "-e", tmpScript}},
-	}
-
-	for _, terminal := range terminals {
-		cmd, err := paths.NewProcess(nil, terminal.cmd...)
-		if err != nil {
-			return err
-		}
-		slog.Info("Trying opening terminal", "command", terminal.cmd)
-		stdout, err := cmd.RunAndCaptureCombinedOutput(context.Background())
-		if err == nil {
-			return nil
-		} else {
-			slog.Warn("[skip] start terminal", "command", terminal.cmd, "error", string(stdout))
-		}
-	}
-
-	terminalNames := make([]string, 0, len(terminals))
-	for _, t := range terminals {
-		if len(t.cmd) > 0 {
-			terminalNames = append(terminalNames, t.cmd[0])
+	e, ok := detectLinuxEmulator()
+	if !ok {
+		names := make([]string, 0, len(linuxEmulators))
+		for _, e := range linuxEmulators {
+			names = append(names, e.Name)
 		}
+		return fmt.Errorf("not supported terminal found: %s, set a custom terminal command in the settings", strings.Join(names, ","))
 	}
-	return fmt.Errorf("not supported terminal found: %s", strings.Join(terminalNames, ","))
+	return launchTemplate(e.Template, args)
 }
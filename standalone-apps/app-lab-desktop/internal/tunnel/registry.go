@@ -0,0 +1,320 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+)
+
+// Info describes an open tunnel.
+type Info struct {
+	Tag       string `json:"tag"`
+	BoardPort int    `json:"boardPort"`
+	LocalPort int    `json:"localPort"`
+	// Reverse is set for the tunnels from the board to the host.
+	Reverse bool `json:"reverse,omitempty"`
+}
+
+// Registry keeps the tunnels opened on a board connection.
+type Registry struct {
+	mu       sync.Mutex
+	tunnels  []*tunnel
+	reverses []*reverseTunnel
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Open returns the tunnel to boardPort, opening it if needed.
+func (r *Registry) Open(ctx context.Context, conn remote.RemoteConn, tag string, boardPort int) (Tunnel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.tunnels {
+		if t.conn == conn && t.boardPort == boardPort && t.shared {
+			// @TODO: If needed by future requirements, allow multiple tunnels to the same port.
+			return t, nil
+		}
+	}
+
+	t, err := New(ctx, conn, tag, boardPort)
+	if err != nil {
+		return nil, err
+	}
+	t.shared = true
+	r.tunnels = append(r.tunnels, t)
+	return t, nil
+}
+
+// OpenWithLocalPort opens a tunnel of its own forwarding localPort to boardPort, not
+// reused by Open. If the local port is not available, it fails, or uses an available
+// one when fallback is set. A tunnel with the same tag is reused.
+func (r *Registry) OpenWithLocalPort(ctx context.Context, conn remote.RemoteConn, tag string, localPort int, boardPort int, fallback bool) (Tunnel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.tunnels {
+		if t.conn == conn && t.tag == tag {
+			return t, nil
+		}
+	}
+
+	t, err := NewWithLocalPort(ctx, conn, tag, localPort, boardPort, fallback)
+	if err != nil {
+		return nil, err
+	}
+	r.tunnels = append(r.tunnels, t)
+	return t, nil
+}
+
+// OpenReverse opens a reverse tunnel forwarding boardPort on the board to localPort
+// on the host. A reverse tunnel from the same board port is reused.
+func (r *Registry) OpenReverse(ctx context.Context, conn remote.RemoteConn, tag string, boardPort int, localPort int) (Info, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.reverses {
+		if t.boardPort == boardPort {
+			if t.localPort != localPort {
+				return Info{}, fmt.Errorf("board port %d is already forwarded to local port %d", boardPort, t.localPort)
+			}
+			return t.info(), nil
+		}
+	}
+
+	t, err := newReverse(ctx, conn, tag, boardPort, localPort)
+	if err != nil {
+		return Info{}, err
+	}
+	r.reverses = append(r.reverses, t)
+	return t.info(), nil
+}
+
+func (r *Registry) List() []Info {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	infos := make([]Info, 0, len(r.tunnels)+len(r.reverses))
+	for _, t := range r.tunnels {
+		infos = append(infos, t.info())
+	}
+	for _, t := range r.reverses {
+		infos = append(infos, t.info())
+	}
+	return infos
+}
+
+// Stats returns the traffic statistics of every tunnel, regular and reverse.
+func (r *Registry) Stats() []Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]Stats, 0, len(r.tunnels)+len(r.reverses))
+	for _, t := range r.tunnels {
+		stats = append(stats, t.Stats())
+	}
+	for _, t := range r.reverses {
+		stats = append(stats, t.Stats())
+	}
+	return stats
+}
+
+// Find returns the first tunnel with the given tag.
+func (r *Registry) Find(tag string) (Tunnel, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.tunnels, func(t *tunnel) bool { return t.tag == tag })
+	if i == -1 {
+		return nil, false
+	}
+	return r.tunnels[i], true
+}
+
+// Snapshot lists the tunnels of a registry, to open them again once the connection
+// has been re-established, see Registry.Restore.
+type Snapshot []snapshotTunnel
+
+type snapshotTunnel struct {
+	info   Info
+	shared bool
+}
+
+// DeleteFunc returns the snapshot without the tunnels for which del returns true.
+func (s Snapshot) DeleteFunc(del func(Info) bool) Snapshot {
+	return slices.DeleteFunc(slices.Clone(s), func(t snapshotTunnel) bool { return del(t.info) })
+}
+
+// Snapshot returns the tunnels of the registry.
+func (r *Registry) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := make(Snapshot, 0, len(r.tunnels)+len(r.reverses))
+	for _, t := range r.tunnels {
+		s = append(s, snapshotTunnel{info: t.info(), shared: t.shared})
+	}
+	for _, t := range r.reverses {
+		s = append(s, snapshotTunnel{info: t.info()})
+	}
+	return s
+}
+
+// Restore opens on conn the tunnels of s that are not open anymore, on the same local
+// ports when available. It returns the tunnels that could not be opened again.
+func (r *Registry) Restore(ctx context.Context, conn remote.RemoteConn, s Snapshot) ([]Info, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var dropped []Info
+	var errs []error
+	for _, st := range s {
+		info := st.info
+		if slices.ContainsFunc(r.tunnels, func(t *tunnel) bool { return t.tag == info.Tag }) ||
+			slices.ContainsFunc(r.reverses, func(t *reverseTunnel) bool { return t.tag == info.Tag }) {
+			continue
+		}
+		if info.Reverse {
+			t, err := newReverse(ctx, conn, info.Tag, info.BoardPort, info.LocalPort)
+			if err != nil {
+				dropped = append(dropped, info)
+				errs = append(errs, err)
+				continue
+			}
+			r.reverses = append(r.reverses, t)
+			continue
+		}
+		t, err := NewWithLocalPort(ctx, conn, info.Tag, info.LocalPort, info.BoardPort, true)
+		if err != nil {
+			dropped = append(dropped, info)
+			errs = append(errs, err)
+			continue
+		}
+		t.shared = st.shared
+		r.tunnels = append(r.tunnels, t)
+	}
+	return dropped, errors.Join(errs...)
+}
+
+// CloseByTag closes the tunnels, regular and reverse, with the given tag.
+func (r *Registry) CloseByTag(ctx context.Context, tag string) error {
+	return r.close(ctx,
+		func(t *tunnel) bool { return t.tag == tag },
+		func(t *reverseTunnel) bool { return t.tag == tag },
+	)
+}
+
+// CloseByPort closes the tunnels, regular and reverse, on the given board port.
+func (r *Registry) CloseByPort(ctx context.Context, boardPort int) error {
+	return r.close(ctx,
+		func(t *tunnel) bool { return t.boardPort == boardPort },
+		func(t *reverseTunnel) bool { return t.boardPort == boardPort },
+	)
+}
+
+// CloseAll closes every tunnel, returning the errors of the ones that failed.
+func (r *Registry) CloseAll(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	var killedAll []remote.RemoteConn
+	for _, t := range r.tunnels {
+		err := t.Close(ctx)
+		if errors.Is(err, ErrCloseUnsupported) {
+			if !slices.Contains(killedAll, t.conn) {
+				killedAll = append(killedAll, t.conn)
+				if err := t.conn.ForwardKillAll(ctx); err != nil {
+					errs = append(errs, fmt.Errorf("failing to kill all port forwards: %w", err))
+				}
+			}
+			t.detach()
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	r.tunnels = nil
+
+	for _, t := range r.reverses {
+		if err := t.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	r.reverses = nil
+	return errors.Join(errs...)
+}
+
+// close closes the matching tunnels. When the connection cannot remove a single
+// forward, all of them are removed and the other tunnels are reopened on the same
+// ports, so that e.g. the orchestrator survives an app UI being closed.
+func (r *Registry) close(ctx context.Context, match func(*tunnel) bool, matchReverse func(*reverseTunnel) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var closing, kept []*tunnel
+	for _, t := range r.tunnels {
+		if match(t) {
+			closing = append(closing, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	var closingReverses, keptReverses []*reverseTunnel
+	for _, t := range r.reverses {
+		if matchReverse(t) {
+			closingReverses = append(closingReverses, t)
+		} else {
+			keptReverses = append(keptReverses, t)
+		}
+	}
+	if len(closing) == 0 && len(closingReverses) == 0 {
+		return fmt.Errorf("tunnel not found")
+	}
+
+	var errs []error
+	for _, t := range closingReverses {
+		if err := t.Close(ctx); err != nil {
+			errs = append(errs, err)
+			keptReverses = append(keptReverses, t)
+		}
+	}
+	r.reverses = keptReverses
+
+	var killedAll []remote.RemoteConn
+	for _, t := range closing {
+		err := t.Close(ctx)
+		if errors.Is(err, ErrCloseUnsupported) {
+			if !slices.Contains(killedAll, t.conn) {
+				if err := t.conn.ForwardKillAll(ctx); err != nil {
+					errs = append(errs, fmt.Errorf("failing to kill all port forwards: %w", err))
+					kept = append(kept, t)
+					continue
+				}
+				killedAll = append(killedAll, t.conn)
+			}
+			t.detach()
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			kept = append(kept, t)
+		}
+	}
+	for _, k := range kept {
+		if slices.Contains(killedAll, k.conn) {
+			if err := k.reopen(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	r.tunnels = kept
+	return errors.Join(errs...)
+}
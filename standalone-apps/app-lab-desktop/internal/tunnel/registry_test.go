@@ -0,0 +1,205 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+)
+
+// fakeConn records the forwards, like a connection that can only remove all of them.
+type fakeConn struct {
+	remote.RemoteConn
+	forwards map[int]int
+}
+
+func (c *fakeConn) Forward(_ context.Context, localPort, remotePort int) error {
+	c.forwards[localPort] = remotePort
+	return nil
+}
+
+func (c *fakeConn) ForwardKillAll(context.Context) error {
+	clear(c.forwards)
+	return nil
+}
+
+// fakeKillerConn can remove a single forward.
+type fakeKillerConn struct {
+	fakeConn
+}
+
+func (c *fakeKillerConn) ForwardKill(_ context.Context, localPort int) error {
+	delete(c.forwards, localPort)
+	return nil
+}
+
+func TestRegistryCloseKeepsOtherTunnels(t *testing.T) {
+	ctx := context.Background()
+	conns := map[string]remote.RemoteConn{
+		"kill all":      &fakeConn{forwards: map[int]int{}},
+		"kill per port": &fakeKillerConn{fakeConn{forwards: map[int]int{}}},
+	}
+
+	for name, conn := range conns {
+		t.Run(name, func(t *testing.T) {
+			r := NewRegistry()
+			orchestrator, err := r.Open(ctx, conn, "orchestrator", 8800)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The port may be in use on the host, another one is used then.
+			localPort, err := orchestrator.Port()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Open(ctx, conn, "7000", 7000); err != nil {
+				t.Fatal(err)
+			}
+			// Opening the same board port again reuses the tunnel.
+			if _, err := r.Open(ctx, conn, "7000", 7000); err != nil {
+				t.Fatal(err)
+			}
+			if n := len(r.List()); n != 2 {
+				t.Fatalf("expected 2 tunnels, got %d", n)
+			}
+
+			if err := r.CloseByPort(ctx, 7000); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := r.List(); !slices.Equal(got, []Info{{Tag: "orchestrator", BoardPort: 8800, LocalPort: localPort}}) {
+				t.Errorf("unexpected tunnels %+v", got)
+			}
+			forwards := forwardsOf(conn)
+			if len(forwards) != 1 || !slices.Contains(slices.Collect(maps.Values(forwards)), 8800) {
+				t.Errorf("expected only the orchestrator forward, got %v", forwards)
+			}
+
+			if err := r.CloseByTag(ctx, "7000"); err == nil {
+				t.Errorf("expected an error closing a closed tunnel")
+			}
+		})
+	}
+}
+
+func TestRegistryOwnTunnelNotShared(t *testing.T) {
+	ctx := context.Background()
+	conn := &fakeKillerConn{fakeConn{forwards: map[int]int{}}}
+
+	r := NewRegistry()
+	if _, err := r.OpenWithLocalPort(ctx, conn, "forward:jupyter", 8888, 8888, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Open(ctx, conn, "8888", 8888); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(r.List()); n != 2 {
+		t.Fatalf("expected 2 tunnels, got %d", n)
+	}
+
+	// Closing the forward keeps the app UI tunnel to the same board port.
+	if err := r.CloseByTag(ctx, "forward:jupyter"); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.List(); len(got) != 1 || got[0].Tag != "8888" {
+		t.Errorf("unexpected tunnels %+v", got)
+	}
+}
+
+// fakeFailingKillerConn fails to remove the forwards.
+type fakeFailingKillerConn struct {
+	fakeConn
+}
+
+func (c *fakeFailingKillerConn) ForwardKill(context.Context, int) error {
+	return errors.New("connection lost")
+}
+
+func TestRegistryCloseFailureKeepsTunnel(t *testing.T) {
+	ctx := context.Background()
+	conn := &fakeFailingKillerConn{fakeConn{forwards: map[int]int{}}}
+
+	r := NewRegistry()
+	if _, err := r.Open(ctx, conn, "7000", 7000); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CloseByTag(ctx, "7000"); err == nil {
+		t.Fatal("expected an error closing the tunnel")
+	}
+	// The forward is still there, so is the tunnel, that can be closed again.
+	if got := r.List(); len(got) != 1 || got[0].Tag != "7000" {
+		t.Errorf("expected the tunnel to be kept, got %+v", got)
+	}
+	if _, ok := r.Find("7000"); !ok {
+		t.Errorf("expected the tunnel to be found")
+	}
+}
+
+// fakeReverseConn records the reverse forwards.
+type fakeReverseConn struct {
+	fakeKillerConn
+	reverses map[int]int
+}
+
+func (c *fakeReverseConn) ReverseForward(_ context.Context, boardPort, localPort int) error {
+	c.reverses[boardPort] = localPort
+	return nil
+}
+
+func (c *fakeReverseConn) ReverseKill(_ context.Context, boardPort int) error {
+	delete(c.reverses, boardPort)
+	return nil
+}
+
+func TestRegistryReverse(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewRegistry()
+	if _, err := r.OpenReverse(ctx, &fakeConn{forwards: map[int]int{}}, "model", 11434, 11434); err == nil {
+		t.Errorf("expected an error for a connection without reverse forwarding")
+	}
+
+	conn := &fakeReverseConn{fakeKillerConn{fakeConn{forwards: map[int]int{}}}, map[int]int{}}
+	orchestrator, err := r.Open(ctx, conn, "orchestrator", 8800)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localPort, err := orchestrator.Port()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.OpenReverse(ctx, conn, "model", 11434, 11434); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.OpenReverse(ctx, conn, "other", 11434, 8080); err == nil {
+		t.Errorf("expected an error forwarding a board port twice")
+	}
+
+	expected := []Info{
+		{Tag: "orchestrator", BoardPort: 8800, LocalPort: localPort},
+		{Tag: "model", BoardPort: 11434, LocalPort: 11434, Reverse: true},
+	}
+	if got := r.List(); !slices.Equal(got, expected) {
+		t.Errorf("unexpected tunnels %+v", got)
+	}
+
+	if err := r.CloseByTag(ctx, "model"); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.reverses) != 0 || len(conn.forwards) != 1 {
+		t.Errorf("expected only the orchestrator forward, got %v and reverses %v", conn.forwards, conn.reverses)
+	}
+}
+
+func forwardsOf(conn remote.RemoteConn) map[int]int {
+	switch c := conn.(type) {
+	case *fakeConn:
+		return c.forwards
+	case *fakeKillerConn:
+		return c.forwards
+	}
+	return nil
+}
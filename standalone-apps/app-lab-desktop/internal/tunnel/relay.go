@@ -0,0 +1,184 @@
+package tunnel
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats reports the traffic relayed by a tunnel since it has been opened.
+type Stats struct {
+	Info
+	BytesToBoard      int64  `json:"bytesToBoard"`
+	BytesFromBoard    int64  `json:"bytesFromBoard"`
+	ActiveConnections int64  `json:"activeConnections"`
+	TotalConnections  int64  `json:"totalConnections"`
+	Errors            int64  `json:"errors"`
+	LastError         string `json:"lastError,omitempty"`
+}
+
+// relay accepts the connections on a host listener and pipes them to target, counting
+// the traffic. It sits in front of the connection port forwarding, whatever the
+// transport, so that every tunnel can be inspected the same way.
+type relay struct {
+	listener net.Listener
+	target   string
+	// reverse is set when the accepted connections come from the board.
+	reverse bool
+
+	toBoard    atomic.Int64
+	fromBoard  atomic.Int64
+	active     atomic.Int64
+	total      atomic.Int64
+	errorCount atomic.Int64
+
+	mu        sync.Mutex
+	lastError string
+	conns     map[net.Conn]struct{}
+	closed    bool
+}
+
+func newRelay(listener net.Listener, target string, reverse bool) *relay {
+	r := &relay{
+		listener: listener,
+		target:   target,
+		reverse:  reverse,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	go r.serve()
+	return r
+}
+
+func (r *relay) port() int {
+	return r.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (r *relay) serve() {
+	for {
+		c, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		go r.handle(c)
+	}
+}
+
+func (r *relay) handle(accepted net.Conn) {
+	r.total.Add(1)
+	r.active.Add(1)
+	defer r.active.Add(-1)
+
+	if !r.track(accepted) {
+		accepted.Close()
+		return
+	}
+	defer r.untrack(accepted)
+
+	dialed, err := net.Dial("tcp", r.target)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	if !r.track(dialed) {
+		dialed.Close()
+		return
+	}
+	defer r.untrack(dialed)
+
+	// The bytes going from the accepted to the dialed connection go to the board,
+	// unless the tunnel is a reverse one.
+	forward, backward := &r.toBoard, &r.fromBoard
+	if r.reverse {
+		forward, backward = backward, forward
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		r.copy(dialed, accepted, forward)
+		done <- struct{}{}
+	}()
+	go func() {
+		r.copy(accepted, dialed, backward)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+func (r *relay) copy(dst, src net.Conn, counter *atomic.Int64) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				r.fail(werr)
+				return
+			}
+			counter.Add(int64(n))
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				r.fail(err)
+			}
+			return
+		}
+	}
+}
+
+// fail records an error, unless it comes from the relay being closed.
+func (r *relay) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || errors.Is(err, net.ErrClosed) {
+		return
+	}
+	r.errorCount.Add(1)
+	r.lastError = err.Error()
+}
+
+func (r *relay) track(c net.Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+	r.conns[c] = struct{}{}
+	return true
+}
+
+func (r *relay) untrack(c net.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.conns, c)
+	c.Close()
+}
+
+func (r *relay) stats(info Info) Stats {
+	r.mu.Lock()
+	lastError := r.lastError
+	r.mu.Unlock()
+
+	return Stats{
+		Info:              info,
+		BytesToBoard:      r.toBoard.Load(),
+		BytesFromBoard:    r.fromBoard.Load(),
+		ActiveConnections: r.active.Load(),
+		TotalConnections:  r.total.Load(),
+		Errors:            r.errorCount.Load(),
+		LastError:         lastError,
+	}
+}
+
+// close stops accepting connections and drops the ones in progress.
+func (r *relay) close() error {
+	r.mu.Lock()
+	r.closed = true
+	for c := range r.conns {
+		c.Close()
+	}
+	clear(r.conns)
+	r.mu.Unlock()
+
+	return r.listener.Close()
+}
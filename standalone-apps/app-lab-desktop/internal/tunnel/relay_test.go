@@ -0,0 +1,81 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+)
+
+// echoConn forwards the local ports to an echo server standing for the board.
+type echoConn struct {
+	remote.RemoteConn
+	listeners []net.Listener
+}
+
+func (c *echoConn) Forward(_ context.Context, localPort, _ int) error {
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)))
+	if err != nil {
+		return err
+	}
+	c.listeners = append(c.listeners, l)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return nil
+}
+
+func (c *echoConn) ForwardKill(context.Context, int) error {
+	for _, l := range c.listeners {
+		l.Close()
+	}
+	return nil
+}
+
+func TestTunnelStats(t *testing.T) {
+	ctx := context.Background()
+	conn := &echoConn{}
+
+	tun, err := New(ctx, conn, "echo", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tun.Close(ctx)
+
+	port, err := tun.Port()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	s := tun.Stats()
+	if s.BytesToBoard != 5 || s.BytesFromBoard != 5 {
+		t.Errorf("expected 5 bytes each way, got %d to and %d from the board", s.BytesToBoard, s.BytesFromBoard)
+	}
+	if s.TotalConnections != 1 || s.Errors != 0 {
+		t.Errorf("expected 1 connection and no errors, got %+v", s)
+	}
+}
@@ -0,0 +1,73 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/arduino/arduino-app-cli/pkg/board/remote"
+)
+
+// ReverseForwarder is implemented by the connections that can forward a port of the
+// board to a port of the host, e.g. with adb reverse or an SSH remote forward.
+type ReverseForwarder interface {
+	ReverseForward(ctx context.Context, boardPort, localPort int) error
+	ReverseKill(ctx context.Context, boardPort int) error
+}
+
+var ErrReverseUnsupported = errors.New("reverse tunnels are not supported by the connection")
+
+// reverseTunnel forwards connections to boardPort<board> to localPort<host>, so
+// that apps on the board can reach services of the host. The board side is forwarded
+// to a relay, that pipes the connections to localPort.
+type reverseTunnel struct {
+	tag       string
+	conn      ReverseForwarder
+	boardPort int
+	localPort int
+	relay     *relay
+}
+
+func newReverse(ctx context.Context, conn remote.RemoteConn, tag string, boardPort int, localPort int) (*reverseTunnel, error) {
+	rf, ok := conn.(ReverseForwarder)
+	if !ok {
+		return nil, ErrReverseUnsupported
+	}
+	listener, err := listenLocal(0)
+	if err != nil {
+		return nil, fmt.Errorf("failing to listen for reverse forward: %w", err)
+	}
+	r := newRelay(listener, net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)), true)
+	if err := rf.ReverseForward(ctx, boardPort, r.port()); err != nil {
+		_ = r.close()
+		return nil, fmt.Errorf("failing to reverse forward port %d<-%d: %w", localPort, boardPort, err)
+	}
+	return &reverseTunnel{
+		tag:       tag,
+		conn:      rf,
+		boardPort: boardPort,
+		localPort: localPort,
+		relay:     r,
+	}, nil
+}
+
+func (t *reverseTunnel) Close(ctx context.Context) error {
+	if t.conn == nil {
+		return nil
+	}
+	if err := t.conn.ReverseKill(ctx, t.boardPort); err != nil {
+		return fmt.Errorf("failing to remove reverse forward %d: %w", t.boardPort, err)
+	}
+	t.conn = nil
+	return t.relay.close()
+}
+
+func (t *reverseTunnel) Stats() Stats {
+	return t.relay.stats(t.info())
+}
+
+func (t *reverseTunnel) info() Info {
+	return Info{Tag: t.tag, BoardPort: t.boardPort, LocalPort: t.localPort, Reverse: true}
+}
@@ -1,5 +1,7 @@
 package tunnel
 
+import "app-lab-desktop/internal/sshconn"
+
 type SSHErrorAuthFailed struct {
 	IsErr   bool   `json:"isSSHErrorAuthFailed"`
 	Message string `json:"message"`
@@ -17,3 +19,29 @@ func NewSSHErrorAuthFailed(err error) SSHErrorAuthFailed {
 		Message: err.Error(),
 	}
 }
+
+type SSHErrorHostKeyMismatch struct {
+	IsErr    bool   `json:"isSSHErrorHostKeyMismatch"`
+	Message  string `json:"message"`
+	Identity string `json:"identity"`
+	Address  string `json:"address"`
+	Expected string `json:"expected"`
+	Got      string `json:"got"`
+}
+
+var _ error = (*SSHErrorHostKeyMismatch)(nil)
+
+func (e SSHErrorHostKeyMismatch) Error() string {
+	return e.Message
+}
+
+func NewSSHErrorHostKeyMismatch(err error, e *sshconn.HostKeyMismatchError) SSHErrorHostKeyMismatch {
+	return SSHErrorHostKeyMismatch{
+		IsErr:    true,
+		Message:  err.Error(),
+		Identity: e.Identity,
+		Address:  e.Address,
+		Expected: e.Expected,
+		Got:      e.Got,
+	}
+}
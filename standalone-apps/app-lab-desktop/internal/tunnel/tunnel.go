@@ -4,21 +4,47 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
 
 	"github.com/arduino/arduino-app-cli/pkg/board/remote"
 	"github.com/arduino/arduino-app-cli/pkg/x/ports"
 )
 
+// forwardAttempts bounds the host ports tried for a port forwarding, another process
+// may take the available port before the connection listens on it.
+const forwardAttempts = 10
+
 type Tunnel interface {
 	Tag() string
 	Port() (int, error)
+	BoardPort() int
+	Stats() Stats
 	Close(ctx context.Context) error
 }
 
+// PortForwardKiller is implemented by the connections that can remove a single port
+// forwarding, instead of all of them.
+type PortForwardKiller interface {
+	ForwardKill(ctx context.Context, localPort int) error
+}
+
+// ErrCloseUnsupported is returned by Tunnel.Close when the connection can only remove
+// all the port forwardings at once.
+var ErrCloseUnsupported = errors.New("closing a single tunnel is not supported by the connection")
+
+// tunnel exposes boardPort on hostPort through a relay, piping the connections to
+// forwardPort, the host side of the connection port forwarding.
 type tunnel struct {
-	tag      string
-	conn     remote.RemoteConn
-	hostPort int
+	tag         string
+	conn        remote.RemoteConn
+	hostPort    int
+	forwardPort int
+	boardPort   int
+	relay       *relay
+	// shared tunnels are reused by the other openings of their board port.
+	shared bool
 }
 
 var _ Tunnel = (*tunnel)(nil)
@@ -29,34 +55,73 @@ func New(ctx context.Context, conn remote.RemoteConn, tag string, boardTargetPor
 	*tunnel,
 	error,
 ) {
-	hostPort := boardTargetPort
+	return NewWithLocalPort(ctx, conn, tag, boardTargetPort, boardTargetPort, true)
+}
 
-	for {
-		err := conn.Forward(ctx, hostPort, boardTargetPort)
-		if err == nil {
-			break
+// NewWithLocalPort creates a tunnel forwarding localPort<host>:boardTargetPort<board>. If
+// localPort cannot be listened on, an available port is used instead only when fallback
+// is set.
+func NewWithLocalPort(ctx context.Context, conn remote.RemoteConn, tag string, localPort int, boardTargetPort int, fallback bool) (
+	*tunnel,
+	error,
+) {
+	// The errors of a port in use differ between the platforms, e.g. WSAEADDRINUSE on
+	// Windows, any failure falls back.
+	listener, err := listenLocal(localPort)
+	if err != nil {
+		if !fallback {
+			return nil, fmt.Errorf("failing to forward port %d->%d: %w: %w", localPort, boardTargetPort, remote.ErrPortAvailable, err)
 		}
-
-		forwardErr := fmt.Errorf("failing to forward port %d->%d: %w", hostPort, boardTargetPort, err)
-
-		if errors.Is(err, remote.ErrPortAvailable) {
-			hostPort, err = ports.GetAvailable()
-			if err != nil {
-				return nil, errors.Join(forwardErr, fmt.Errorf("failing to get an available port: %w", err))
-			}
-			continue
+		slog.Warn("tunnel port not available, using an available one", "port", localPort, "err", err)
+		if listener, err = listenLocal(0); err != nil {
+			return nil, fmt.Errorf("failing to listen on an available port: %w", err)
 		}
+	}
 
-		return nil, forwardErr
+	forwardPort, err := forward(ctx, conn, boardTargetPort)
+	if err != nil {
+		listener.Close()
+		return nil, err
 	}
 
+	r := newRelay(listener, net.JoinHostPort("127.0.0.1", strconv.Itoa(forwardPort)), false)
 	return &tunnel{
-		tag:      tag,
-		conn:     conn,
-		hostPort: hostPort,
+		tag:         tag,
+		conn:        conn,
+		hostPort:    r.port(),
+		forwardPort: forwardPort,
+		boardPort:   boardTargetPort,
+		relay:       r,
 	}, nil
 }
 
+func listenLocal(port int) (net.Listener, error) {
+	return net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+}
+
+// forward forwards an available host port to boardPort and returns it.
+func forward(ctx context.Context, conn remote.RemoteConn, boardPort int) (int, error) {
+	var err error
+	for range forwardAttempts {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, ctxErr
+		}
+		var port int
+		port, err = ports.GetAvailable()
+		if err != nil {
+			return 0, fmt.Errorf("failing to get an available port: %w", err)
+		}
+		err = conn.Forward(ctx, port, boardPort)
+		if err == nil {
+			return port, nil
+		}
+		if !errors.Is(err, remote.ErrPortAvailable) {
+			return 0, fmt.Errorf("failing to forward port %d->%d: %w", port, boardPort, err)
+		}
+	}
+	return 0, fmt.Errorf("failing to forward port %d after %d attempts: %w", boardPort, forwardAttempts, err)
+}
+
 func (t *tunnel) Tag() string {
 	return t.tag
 }
@@ -68,15 +133,56 @@ func (t *tunnel) Port() (int, error) {
 	return t.hostPort, nil
 }
 
-// Close removes the port forwarding and reinitializes the tunnel properties
+func (t *tunnel) BoardPort() int {
+	return t.boardPort
+}
+
+// Close removes the port forwarding and reinitializes the tunnel properties. It
+// returns ErrCloseUnsupported, leaving the tunnel open, if the connection cannot
+// remove a single port forwarding.
 func (t *tunnel) Close(ctx context.Context) error {
-	// @TODO: close it by port when supported by the remote interface
-	if err := t.conn.ForwardKillAll(ctx); err != nil {
-		return fmt.Errorf("failing to kill all port forwards: %w", err)
+	if t.conn == nil {
+		return nil
+	}
+	killer, ok := t.conn.(PortForwardKiller)
+	if !ok {
+		return ErrCloseUnsupported
+	}
+	if err := killer.ForwardKill(ctx, t.forwardPort); err != nil {
+		return fmt.Errorf("failing to remove port forward %d: %w", t.forwardPort, err)
 	}
 
-	t.conn = nil
-	t.hostPort = 0
+	t.detach()
+	return nil
+}
+
+// Stats returns the traffic relayed by the tunnel.
+func (t *tunnel) Stats() Stats {
+	return t.relay.stats(t.info())
+}
 
+func (t *tunnel) info() Info {
+	return Info{Tag: t.tag, BoardPort: t.boardPort, LocalPort: t.hostPort}
+}
+
+// reopen forwards again the same ports, after all the forwards of the connection
+// have been removed.
+func (t *tunnel) reopen(ctx context.Context) error {
+	if t.conn == nil {
+		return nil
+	}
+	if err := t.conn.Forward(ctx, t.forwardPort, t.boardPort); err != nil {
+		return fmt.Errorf("failing to forward port %d->%d: %w", t.forwardPort, t.boardPort, err)
+	}
 	return nil
 }
+
+// detach stops the relay, once the port forwarding has been removed.
+func (t *tunnel) detach() {
+	if err := t.relay.close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		slog.Warn("failed to close tunnel relay", "tag", t.tag, "err", err)
+	}
+	t.conn = nil
+	t.hostPort = 0
+	t.forwardPort = 0
+}
@@ -0,0 +1,384 @@
+// Package vault stores secrets, such as board passwords, encrypted at rest with
+// AES-256-GCM. The key is either derived from a user passphrase with Argon2id, or
+// random and kept in a key file next to the vault, only readable by the user.
+package vault
+
+import (
+	"app-lab-desktop/internal/config"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	fileVersion = 1
+	keySize     = 32
+	saltSize    = 16
+	checkValue  = "arduino-app-lab"
+)
+
+var (
+	ErrLocked          = errors.New("credential vault is locked")
+	ErrWrongPassphrase = errors.New("wrong credential vault passphrase")
+	ErrNotFound        = errors.New("credential not found")
+)
+
+type kdfParams struct {
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+type sealedEntry struct {
+	// Secret is the nonce followed by the ciphertext, the identity is the
+	// additional data so that an entry cannot be moved to another identity.
+	Secret    []byte    `json:"secret"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type vaultFile struct {
+	Version int `json:"version"`
+	// KDF is set when the key is derived from a passphrase, the key file is used otherwise.
+	KDF     *kdfParams             `json:"kdf,omitempty"`
+	Check   []byte                 `json:"check"`
+	Entries map[string]sealedEntry `json:"entries"`
+}
+
+type Entry struct {
+	Identity  string    `json:"identity"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Status struct {
+	Passphrase bool `json:"passphrase"`
+	Locked     bool `json:"locked"`
+	Entries    int  `json:"entries"`
+}
+
+type Vault struct {
+	path    string
+	keyPath string
+
+	mu   sync.Mutex
+	file vaultFile
+	// key is nil while the vault is locked.
+	key []byte
+}
+
+// Open loads the vault at path, creating it with a new key file at keyPath if it
+// does not exist. A vault protected by a passphrase is locked until Unlock.
+func Open(path string, keyPath string) (*Vault, error) {
+	v := &Vault{path: path, keyPath: keyPath}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := newKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := config.AtomicWriteFile(keyPath, key); err != nil {
+			return nil, err
+		}
+		v.key = key
+		v.file = vaultFile{Version: fileVersion, Entries: map[string]sealedEntry{}}
+		if v.file.Check, err = seal(key, checkValue, ""); err != nil {
+			return nil, err
+		}
+		if err := v.save(); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential vault: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &v.file); err != nil {
+		return nil, fmt.Errorf("failed to decode credential vault: %w", err)
+	}
+	if v.file.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported credential vault version %d", v.file.Version)
+	}
+	if v.file.Entries == nil {
+		v.file.Entries = map[string]sealedEntry{}
+	}
+
+	if v.file.KDF == nil {
+		key, err := v.readKeyFile()
+		if err != nil {
+			return nil, err
+		}
+		v.key = key
+	}
+	return v, nil
+}
+
+// readKeyFile returns the key of the vault, taking the pending key file of a rotation
+// interrupted after the vault was saved.
+func (v *Vault) readKeyFile() ([]byte, error) {
+	key, err := os.ReadFile(v.keyPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read credential vault key: %w", err)
+	}
+	if err == nil && v.checkKey(key) == nil {
+		return key, nil
+	}
+
+	pending, pendingErr := os.ReadFile(v.pendingKeyPath())
+	if pendingErr != nil || v.checkKey(pending) != nil {
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credential vault key: %w", err)
+		}
+		return nil, v.checkKey(key)
+	}
+	if err := os.Rename(v.pendingKeyPath(), v.keyPath); err != nil {
+		return nil, fmt.Errorf("failed to replace credential vault key: %w", err)
+	}
+	return pending, nil
+}
+
+// Unlock derives the key from the passphrase. It is a no-op for a vault using a key file.
+func (v *Vault) Unlock(passphrase string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.file.KDF == nil {
+		return nil
+	}
+	key := deriveKey(passphrase, v.file.KDF)
+	if err := v.checkKey(key); err != nil {
+		return err
+	}
+	v.key = key
+	return nil
+}
+
+// Lock forgets the key of a vault protected by a passphrase.
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.file.KDF != nil {
+		v.key = nil
+	}
+}
+
+func (v *Vault) Status() Status {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return Status{
+		Passphrase: v.file.KDF != nil,
+		Locked:     v.key == nil,
+		Entries:    len(v.file.Entries),
+	}
+}
+
+func (v *Vault) Get(identity string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return "", ErrLocked
+	}
+	e, ok := v.file.Entries[identity]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return open(v.key, e.Secret, identity)
+}
+
+func (v *Vault) Put(identity string, secret string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return ErrLocked
+	}
+	sealed, err := seal(v.key, secret, identity)
+	if err != nil {
+		return err
+	}
+	v.file.Entries[identity] = sealedEntry{Secret: sealed, UpdatedAt: time.Now()}
+	return v.save()
+}
+
+// Forget removes the secret of identity, if any. It works on a locked vault too.
+func (v *Vault) Forget(identity string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, ok := v.file.Entries[identity]; !ok {
+		return nil
+	}
+	delete(v.file.Entries, identity)
+	return v.save()
+}
+
+// List returns the identities with a stored secret, the secrets stay encrypted.
+func (v *Vault) List() []Entry {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entries := make([]Entry, 0, len(v.file.Entries))
+	for _, id := range slices.Sorted(maps.Keys(v.file.Entries)) {
+		entries = append(entries, Entry{Identity: id, UpdatedAt: v.file.Entries[id].UpdatedAt})
+	}
+	return entries
+}
+
+// Rotate re-encrypts every secret with a new key, derived from passphrase or, if
+// empty, random and kept in the key file. The new key file is written aside and
+// replaces the previous one once the vault is saved, so that a failure leaves the
+// vault readable.
+func (v *Vault) Rotate(passphrase string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return ErrLocked
+	}
+
+	secrets := make(map[string]string, len(v.file.Entries))
+	for id, e := range v.file.Entries {
+		s, err := open(v.key, e.Secret, id)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt credential of %s: %w", id, err)
+		}
+		secrets[id] = s
+	}
+
+	next := vaultFile{Version: fileVersion, Entries: make(map[string]sealedEntry, len(secrets))}
+	var key []byte
+	if passphrase == "" {
+		var err error
+		if key, err = newKey(); err != nil {
+			return err
+		}
+		if err := config.AtomicWriteFile(v.pendingKeyPath(), key); err != nil {
+			return err
+		}
+	} else {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		next.KDF = &kdfParams{Salt: salt, Time: 3, Memory: 64 * 1024, Threads: 4}
+		key = deriveKey(passphrase, next.KDF)
+	}
+
+	var err error
+	if next.Check, err = seal(key, checkValue, ""); err != nil {
+		_ = os.Remove(v.pendingKeyPath())
+		return err
+	}
+	for id, s := range secrets {
+		sealed, err := seal(key, s, id)
+		if err != nil {
+			_ = os.Remove(v.pendingKeyPath())
+			return err
+		}
+		next.Entries[id] = sealedEntry{Secret: sealed, UpdatedAt: v.file.Entries[id].UpdatedAt}
+	}
+
+	prev := v.file
+	v.file = next
+	if err := v.save(); err != nil {
+		v.file = prev
+		_ = os.Remove(v.pendingKeyPath())
+		return err
+	}
+	v.key = key
+
+	if next.KDF == nil {
+		if err := os.Rename(v.pendingKeyPath(), v.keyPath); err != nil {
+			return fmt.Errorf("failed to replace credential vault key: %w", err)
+		}
+	} else {
+		// The key file of the previous key would allow decrypting old copies of the vault.
+		if err := os.Remove(v.keyPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove credential vault key: %w", err)
+		}
+	}
+	return nil
+}
+
+func (v *Vault) checkKey(key []byte) error {
+	value, err := open(key, v.file.Check, "")
+	if err != nil || value != checkValue {
+		if v.file.KDF != nil {
+			return ErrWrongPassphrase
+		}
+		return fmt.Errorf("credential vault key does not match the vault")
+	}
+	return nil
+}
+
+func (v *Vault) pendingKeyPath() string {
+	return v.keyPath + ".next"
+}
+
+func newKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate credential vault key: %w", err)
+	}
+	return key, nil
+}
+
+func (v *Vault) save() error {
+	data, err := json.MarshalIndent(v.file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credential vault: %w", err)
+	}
+	return config.AtomicWriteFile(v.path, data)
+}
+
+func deriveKey(passphrase string, p *kdfParams) []byte {
+	return argon2.IDKey([]byte(passphrase), p.Salt, p.Time, p.Memory, p.Threads, keySize)
+}
+
+func seal(key []byte, plaintext string, identity string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, []byte(plaintext), []byte(identity)), nil
+}
+
+func open(key []byte, sealed []byte, identity string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid sealed secret")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(identity))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential vault key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,168 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestVault(t *testing.T, dir string) *Vault {
+	t.Helper()
+	v, err := Open(filepath.Join(dir, "credentials.vault"), filepath.Join(dir, "credentials.key"))
+	if err != nil {
+		t.Fatalf("failed to open vault: %v", err)
+	}
+	return v
+}
+
+func TestVaultKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	v := openTestVault(t, dir)
+
+	if err := v.Put("serial:SN1", "s3cr3t-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret is not stored in clear.
+	data, err := os.ReadFile(filepath.Join(dir, "credentials.vault"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("s3cr3t-password")) {
+		t.Errorf("vault file contains the secret in clear")
+	}
+
+	v = openTestVault(t, dir)
+	got, err := v.Get("serial:SN1")
+	if err != nil || got != "s3cr3t-password" {
+		t.Fatalf("Get() = %q, %v, expected s3cr3t-password", got, err)
+	}
+	if _, err := v.Get("serial:SN2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := v.Forget("serial:SN1"); err != nil {
+		t.Fatal(err)
+	}
+	if entries := v.List(); len(entries) != 0 {
+		t.Errorf("expected no entries, got %v", entries)
+	}
+}
+
+func TestVaultRotatePassphrase(t *testing.T) {
+	dir := t.TempDir()
+	v := openTestVault(t, dir)
+	if err := v.Put("serial:SN1", "s3cr3t-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Rotate("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "credentials.key")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the key file to be removed, got %v", err)
+	}
+
+	v = openTestVault(t, dir)
+	if s := v.Status(); !s.Passphrase || !s.Locked || s.Entries != 1 {
+		t.Errorf("unexpected status %+v", s)
+	}
+	if _, err := v.Get("serial:SN1"); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+	if err := v.Unlock("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if err := v.Unlock("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Get("serial:SN1"); err != nil || got != "s3cr3t-password" {
+		t.Fatalf("Get() = %q, %v, expected s3cr3t-password", got, err)
+	}
+
+	// Back to a key file.
+	if err := v.Rotate(""); err != nil {
+		t.Fatal(err)
+	}
+	v = openTestVault(t, dir)
+	if got, err := v.Get("serial:SN1"); err != nil || got != "s3cr3t-password" {
+		t.Fatalf("Get() = %q, %v, expected s3cr3t-password", got, err)
+	}
+}
+
+func TestVaultEntryBoundToIdentity(t *testing.T) {
+	v := openTestVault(t, t.TempDir())
+	if err := v.Put("serial:SN1", "s3cr3t-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	v.file.Entries["serial:SN2"] = v.file.Entries["serial:SN1"]
+	if _, err := v.Get("serial:SN2"); err == nil {
+		t.Errorf("expected an entry moved to another identity not to decrypt")
+	}
+}
+
+func TestVaultRotateFailureKeepsKey(t *testing.T) {
+	dir := t.TempDir()
+	v := openTestVault(t, dir)
+	if err := v.Put("serial:SN1", "s3cr3t-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A non-empty directory in place of the vault makes the save fail.
+	vaultPath := filepath.Join(dir, "credentials.vault")
+	data, err := os.ReadFile(vaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(vaultPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(vaultPath, "x"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Rotate(""); err == nil {
+		t.Fatal("expected the rotation to fail")
+	}
+	if err := os.RemoveAll(vaultPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(vaultPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v = openTestVault(t, dir)
+	if got, err := v.Get("serial:SN1"); err != nil || got != "s3cr3t-password" {
+		t.Fatalf("Get() = %q, %v, expected s3cr3t-password", got, err)
+	}
+}
+
+func TestVaultRotateInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	v := openTestVault(t, dir)
+	if err := v.Put("serial:SN1", "s3cr3t-password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Rotate(""); err != nil {
+		t.Fatal(err)
+	}
+
+	// As if the app stopped between saving the vault and replacing the key file.
+	keyPath := filepath.Join(dir, "credentials.key")
+	if err := os.Rename(keyPath, keyPath+".next"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, bytes.Repeat([]byte{1}, keySize), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v = openTestVault(t, dir)
+	if got, err := v.Get("serial:SN1"); err != nil || got != "s3cr3t-password" {
+		t.Fatalf("Get() = %q, %v, expected s3cr3t-password", got, err)
+	}
+	if _, err := os.Stat(keyPath + ".next"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the pending key file to replace the key file, got %v", err)
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"app-lab-desktop/internal/app"
+	"app-lab-desktop/internal/applog"
+	"app-lab-desktop/internal/cli"
 	"app-lab-desktop/internal/learn"
 	"embed"
 	"fmt"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -20,6 +23,16 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(version, os.Args[1:]))
+	}
+
+	if logs, err := applog.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up the log file: %v\n", err)
+	} else {
+		defer logs.Close()
+	}
+
 	learnSvc := learn.New()
 	app := app.New(version, learnSvc)
 
@@ -36,6 +49,7 @@ func main() {
 		OnShutdown:       app.Shutdown,
 		Bind:             []any{app},
 		ErrorFormatter:   app.GetErrorFormatter(),
+		Logger:           applog.WailsLogger{},
 		Debug: options.Debug{
 			OpenInspectorOnStartup: true,
 		},
@@ -56,10 +70,3 @@ func main() {
 		panic(fmt.Errorf("failed to run application: %w", err))
 	}
 }
-
-func printHelp(cmd string) {
-	fmt.Printf("Usage: %s [command]\n", cmd)
-	fmt.Println("Commands:")
-	fmt.Println("  version   Show the application version")
-	fmt.Println("  help      Show this help message")
-}